@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os/signal"
+	"syscall"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/data"
+	"github.com/zy99978455-otw/flash-monitor/internal/validator"
+)
+
+func (app *application) createBackfillHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		FromBlock *int64 `json:"from_block"`
+		ToBlock   *int64 `json:"to_block"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.FromBlock != nil, "from_block", "must be provided")
+	v.Check(input.ToBlock != nil, "to_block", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	job := &data.BackfillJob{
+		FromBlock: *input.FromBlock,
+		ToBlock:   *input.ToBlock,
+	}
+
+	if data.ValidateBackfillJob(v, job); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.BackfillJobs.Insert(job)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.startBackfill(job)
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/admin/backfills/%d", job.ID))
+
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"backfill": job}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showBackfillHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	job, err := app.models.BackfillJobs.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"backfill": job}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// resumeBackfillHandler 重新启动失败或被中断的回填任务，从任务自身的游标继续
+func (app *application) resumeBackfillHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	job, err := app.models.BackfillJobs.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if job.Status == data.BackfillCompleted {
+		app.editConflictResponse(w, r, "backfill job has already completed")
+		return
+	}
+
+	if !app.startBackfill(job) {
+		app.editConflictResponse(w, r, "backfill job is already running")
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"backfill": job}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// startBackfill 在后台执行回填任务，同一任务同时只允许一个协程运行
+// 返回 false 表示该任务已在运行
+func (app *application) startBackfill(job *data.BackfillJob) bool {
+	app.backfillMu.Lock()
+	defer app.backfillMu.Unlock()
+
+	if app.runningBackfills == nil {
+		app.runningBackfills = make(map[int64]bool)
+	}
+	if app.runningBackfills[job.ID] {
+		return false
+	}
+	app.runningBackfills[job.ID] = true
+
+	// 复制一份，避免后台协程与 HTTP 响应序列化同时访问同一个结构体
+	running := *job
+
+	app.background(func() {
+		defer func() {
+			app.backfillMu.Lock()
+			delete(app.runningBackfills, running.ID)
+			app.backfillMu.Unlock()
+		}()
+
+		if err := app.engine.Backfill(app.ctx, &running); err != nil && app.ctx.Err() == nil {
+			app.logger.Error("backfill job failed", "job_id", running.ID, "error", err)
+		}
+	})
+	return true
+}
+
+// resumeBackfills 启动时恢复所有未完成的回填任务
+func (app *application) resumeBackfills() {
+	jobs, err := app.models.BackfillJobs.GetUnfinished()
+	if err != nil {
+		app.logger.Error("failed to load unfinished backfill jobs", "error", err)
+		return
+	}
+
+	for _, job := range jobs {
+		app.logger.Info("resuming backfill job", "job_id", job.ID, "next_block", job.NextBlock, "to_block", job.ToBlock)
+		app.startBackfill(job)
+	}
+}
+
+// backfillCommand 实现 `backfill` 子命令：前台执行一次回填，Ctrl+C 中断后可用 -job 续跑
+func (app *application) backfillCommand(args []string) error {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+
+	fromBlock := fs.Int64("from", -1, "First block to backfill (inclusive)")
+	toBlock := fs.Int64("to", -1, "Last block to backfill (inclusive)")
+	jobID := fs.Int64("job", 0, "Resume an existing backfill job by ID instead of creating a new one")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var job *data.BackfillJob
+
+	if *jobID > 0 {
+		existing, err := app.models.BackfillJobs.Get(*jobID)
+		if err != nil {
+			return fmt.Errorf("load backfill job %d: %w", *jobID, err)
+		}
+		job = existing
+	} else {
+		job = &data.BackfillJob{FromBlock: *fromBlock, ToBlock: *toBlock}
+
+		v := validator.New()
+		if data.ValidateBackfillJob(v, job); !v.Valid() {
+			return fmt.Errorf("invalid backfill range: %v", v.Errors)
+		}
+
+		if err := app.models.BackfillJobs.Insert(job); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	app.logger.Info("running backfill from command line", "job_id", job.ID, "next_block", job.NextBlock, "to_block", job.ToBlock)
+
+	return app.engine.Backfill(ctx, job)
+}
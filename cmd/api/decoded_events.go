@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zy99978455-otw/flash-monitor/internal/data"
+	"github.com/zy99978455-otw/flash-monitor/internal/validator"
+)
+
+func (app *application) listDecodedEventsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		ContractAddress string
+		EventName       string
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.ContractAddress = app.readString(qs, "contract_address", "")
+	input.EventName = app.readString(qs, "event", "")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "-block_number")
+	input.Filters.SortSafelist = []string{"block_number", "block_time", "-block_number", "-block_time"}
+
+	if input.ContractAddress != "" {
+		v.Check(validator.IsEthAddress(input.ContractAddress), "contract_address", "必须是合法的以太坊16进制地址格式")
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// 与写入时一致使用 EIP-55 校验和格式，查询可以直接走 (contract_address, event_name) 索引
+	if input.ContractAddress != "" {
+		input.ContractAddress = common.HexToAddress(input.ContractAddress).Hex()
+	}
+
+	events, metadata, err := app.models.DecodedEvents.GetAll(input.ContractAddress, input.EventName, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"data": events, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"log"
 	"log/slog"
 	"os"
-	"strings"
 	"sync"
 	"time"
 
@@ -15,6 +14,7 @@ import (
 	_ "github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 	"github.com/zy99978455-otw/flash-monitor/internal/data"
+	"github.com/zy99978455-otw/flash-monitor/internal/decoder"
 	"github.com/zy99978455-otw/flash-monitor/internal/indexer"
 	"github.com/zy99978455-otw/flash-monitor/internal/rpc"
 )
@@ -37,7 +37,35 @@ type config struct {
 		enabled bool
 	}
 	rpc struct {
-		urls string //单节点变更为支持逗号分割的多节点配置
+		// 节点配置文件（JSON），指定后忽略下面按节点拆分的逗号分隔参数
+		configFile string
+		urls       string //单节点变更为支持逗号分割的多节点配置
+		weights    string // 与 urls 一一对应的权重，逗号分隔
+		// 与 urls 一一对应的客户端限流参数，逗号分隔
+		rps        string
+		burst      string
+		dailyLimit string
+		// 与 urls 一一对应的节点能力声明，逗号分隔
+		archive     string
+		traces      string
+		maxLogRange string
+		timeout     time.Duration
+		strategy    string
+		maxLag      uint64
+		breaker     rpc.BreakerConfig
+		hedge       rpc.HedgeConfig
+		quarantine  time.Duration
+		// 录制 JSON-RPC 往返的文件路径，为空时不录制
+		recordFile string
+	}
+	// 监控代币配置文件（可选），启动时写入 tokens 表
+	tokensFile string
+	// ABI 文件目录（可选），其中注册的事件会被解码写入 decoded_events
+	abiDir  string
+	indexer indexer.Config
+	// 管理接口鉴权令牌，为空时禁用所有 /v1/admin 接口
+	admin struct {
+		token string
 	}
 }
 
@@ -51,6 +79,15 @@ type application struct {
 	// 将 NodeManager 注入到全局 application 结构体中
 	nodeManager *rpc.Manager
 
+	// 抓取引擎，管理接口（如历史回填）也通过它执行
+	engine *indexer.Engine
+	// 引擎与后台任务共享的根上下文，停机时由 cancelEngine 取消
+	ctx context.Context
+
+	// 正在运行的回填任务，防止同一任务被重复启动
+	backfillMu       sync.Mutex
+	runningBackfills map[int64]bool
+
 	// 这是一个用来远程关闭引擎的函数开关
 	cancelEngine context.CancelFunc
 }
@@ -73,6 +110,43 @@ func main() {
 
 	// 读取 ETH_RPC_URLS
 	flag.StringVar(&cfg.rpc.urls, "rpc-urls", os.Getenv("ETH_RPC_URLS"), "Comma-separated Ethereum RPC Node URLs")
+	flag.StringVar(&cfg.rpc.configFile, "rpc-config", os.Getenv("ETH_RPC_CONFIG"), "Path to JSON file describing RPC nodes (overrides -rpc-urls and the per-node -rpc-* lists)")
+	flag.DurationVar(&cfg.rpc.timeout, "rpc-timeout", 10*time.Second, "Default per-node RPC timeout")
+	flag.StringVar(&cfg.rpc.weights, "rpc-weights", os.Getenv("ETH_RPC_WEIGHTS"), "Comma-separated node weights matching -rpc-urls (used by the weighted strategy)")
+	flag.StringVar(&cfg.rpc.rps, "rpc-rps", os.Getenv("ETH_RPC_RPS"), "Comma-separated per-node requests-per-second limits matching -rpc-urls (empty = unlimited)")
+	flag.StringVar(&cfg.rpc.burst, "rpc-burst", os.Getenv("ETH_RPC_BURST"), "Comma-separated per-node token bucket sizes matching -rpc-urls")
+	flag.StringVar(&cfg.rpc.dailyLimit, "rpc-daily-limit", os.Getenv("ETH_RPC_DAILY_LIMIT"), "Comma-separated per-node daily request caps (UTC day) matching -rpc-urls (empty = unlimited)")
+	flag.StringVar(&cfg.rpc.archive, "rpc-archive", os.Getenv("ETH_RPC_ARCHIVE"), "Comma-separated true/false flags matching -rpc-urls marking archive nodes (historical queries are routed to them)")
+	flag.StringVar(&cfg.rpc.traces, "rpc-traces", os.Getenv("ETH_RPC_TRACES"), "Comma-separated true/false flags matching -rpc-urls marking nodes that serve debug_/trace_ methods")
+	flag.StringVar(&cfg.rpc.maxLogRange, "rpc-max-log-range", os.Getenv("ETH_RPC_MAX_LOG_RANGE"), "Comma-separated per-node eth_getLogs block range limits matching -rpc-urls (empty = unknown)")
+	flag.Uint64Var(&cfg.rpc.maxLag, "rpc-max-block-lag", 10, "Mark nodes unhealthy when they fall more than N blocks behind the best known head")
+	flag.IntVar(&cfg.rpc.breaker.ConsecutiveFailures, "rpc-breaker-failures", 3, "Open a node's circuit breaker after N consecutive failures")
+	flag.Float64Var(&cfg.rpc.breaker.FailureRate, "rpc-breaker-failure-rate", 0.5, "Open a node's circuit breaker when the failure rate within the window reaches this ratio")
+	flag.IntVar(&cfg.rpc.breaker.WindowSize, "rpc-breaker-window", 20, "Number of recent requests used to compute a node's failure rate")
+	flag.DurationVar(&cfg.rpc.breaker.Cooldown, "rpc-breaker-cooldown", 30*time.Second, "How long an open circuit waits before letting a half-open probe through")
+	flag.BoolVar(&cfg.rpc.hedge.Enabled, "rpc-hedge", false, "Send latency-critical calls (chain head, live eth_getLogs) to a second node when the first one is slow")
+	flag.Float64Var(&cfg.rpc.hedge.Percentile, "rpc-hedge-percentile", 0.95, "Hedge once a call has been outstanding longer than this latency percentile")
+	flag.DurationVar(&cfg.rpc.hedge.MaxDelay, "rpc-hedge-max-delay", 2*time.Second, "Upper bound of the hedging delay, also used until enough latency samples are collected")
+	flag.DurationVar(&cfg.rpc.quarantine, "rpc-quarantine", 5*time.Minute, "How long a node whose block hashes disagree with the quorum is kept out of rotation")
+	flag.StringVar(&cfg.rpc.recordFile, "rpc-record", "", "Record every HTTP JSON-RPC request/response to this fixture file on shutdown (for offline replay tests)")
+	flag.StringVar(&cfg.rpc.strategy, "rpc-strategy", os.Getenv("ETH_RPC_STRATEGY"), "Node selection strategy (priority|weighted|latency, default priority)")
+
+	// 监控代币配置
+	flag.StringVar(&cfg.tokensFile, "tokens-file", os.Getenv("TOKENS_FILE"), "Path to JSON file describing watched ERC20 tokens")
+
+	// 通用事件解码配置
+	flag.StringVar(&cfg.abiDir, "abi-dir", os.Getenv("ABI_DIR"), "Directory of ABI JSON files whose events are decoded into decoded_events")
+
+	// 最终性配置：确认深度或节点的 safe/finalized 标签
+	flag.Int64Var(&cfg.indexer.Confirmations, "indexer-confirmations", 0, "Only index blocks at least N blocks below the chain head")
+	flag.Int64Var(&cfg.indexer.MaxLogRange, "indexer-max-log-range", 2000, "Upper bound of the adaptive eth_getLogs block range")
+	flag.IntVar(&cfg.indexer.CatchupWorkers, "indexer-catchup-workers", 4, "Concurrent log fetchers used when the indexer is far behind (<=1 disables)")
+	flag.Int64Var(&cfg.indexer.MaxReorgDepth, "indexer-max-reorg-depth", 64, "Halt the indexer instead of rolling back reorgs deeper than this")
+	flag.IntVar(&cfg.indexer.Quorum, "indexer-quorum", 0, "Require N nodes to agree on block hashes used for reorg detection and cursors (<=1 trusts a single node)")
+	flag.StringVar(&cfg.indexer.FinalityTag, "indexer-finality-tag", os.Getenv("INDEXER_FINALITY_TAG"), "Follow the node's block tag instead of confirmations (latest|safe|finalized)")
+
+	// 管理接口
+	flag.StringVar(&cfg.admin.token, "admin-token", os.Getenv("ADMIN_TOKEN"), "Bearer token required by /v1/admin endpoints (empty disables them)")
 
 	// 限流器配置
 	flag.Float64Var(&cfg.limiter.rps, "limiter-rps", 2, "Rate limiter maximum requests per second")
@@ -84,6 +158,11 @@ func main() {
 	// 初始化日志
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
+	if err := cfg.indexer.Validate(); err != nil {
+		logger.Error("invalid indexer configuration", "error", err)
+		os.Exit(1)
+	}
+
 	// 建立数据库连接池
 	db, err := openDB(cfg)
 	if err != nil {
@@ -97,24 +176,24 @@ func main() {
 	//// =========================================================================
 	// [V2 改造核心] 解析多节点配置并初始化 NodeManager
 	// =========================================================================
-	rawUrls := strings.Split(cfg.rpc.urls, ",")
-	var nodeConfigs []rpc.NodeConfig
-	for i, u := range rawUrls {
-		u = strings.TrimSpace(u)
-		if u == "" {
-			continue
-		}
-
-		// 动态生成节点配置，按照书写顺序决定优先级
-		nodeConfigs = append(nodeConfigs, rpc.NodeConfig{
-			Name:     "Node-" + string(rune('A'+i)),
-			URL:      u,
-			Priority: i + 1,
-			Timeout:  10 * time.Second,
-		})
+	nodeConfigs, err := cfg.nodeConfigs()
+	if err != nil {
+		logger.Error("invalid rpc node configuration", "error", err)
+		os.Exit(1)
 	}
-
-	nodeManager, err := rpc.NewManager(nodeConfigs, logger)
+	if cfg.rpc.configFile != "" && cfg.rpc.urls != "" {
+		logger.Warn("rpc config file is set, ignoring -rpc-urls", "file", cfg.rpc.configFile)
+	}
+	nodeManager, err := rpc.NewManager(nodeConfigs, rpc.Options{
+		Strategy:    cfg.rpc.strategy,
+		MaxBlockLag: cfg.rpc.maxLag,
+		Breaker:     cfg.rpc.breaker,
+		Hedge:       cfg.rpc.hedge,
+		Quarantine:  cfg.rpc.quarantine,
+		RecordFile:  cfg.rpc.recordFile,
+		// quorum 需要足够多的节点投票，运行时摘除节点同样不能低于它
+		MinNodes: cfg.indexer.Quorum,
+	}, logger)
 	if err != nil {
 		logger.Error("failed to initialize rpc node manager", "error", err)
 		os.Exit(1)
@@ -136,16 +215,47 @@ func main() {
 		models:       data.NewModels(db),
 		broker:       broker,
 		nodeManager:  nodeManager,
+		ctx:          ctx,
 		cancelEngine: cancel,
 	}
 
+	if cfg.tokensFile != "" {
+		if err := app.loadTokensFile(cfg.tokensFile); err != nil {
+			logger.Error("failed to load watched tokens", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if cfg.abiDir != "" {
+		decoders, err := decoder.LoadDir(cfg.abiDir)
+		if err != nil {
+			logger.Error("failed to load abi files", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("registered abi events", "dir", cfg.abiDir, "events", decoders.Len())
+		cfg.indexer.Decoders = decoders
+	}
+
 	// [V2 改造] 初始化抓取引擎。
-	engine := indexer.NewEngine(app.nodeManager, app.models, app.logger, broker.Broadcast)
-	if err != nil {
-		logger.Error("failed to initialize indexer engine", "error", err)
-		os.Exit(1)
+	// 内置处理器：先写库，事务提交后再推送给 SSE 客户端
+	engine := indexer.NewEngine(app.nodeManager, app.models, app.logger, cfg.indexer,
+		indexer.NewPostgresWriter(app.models),
+		indexer.NewBroadcaster(broker.Broadcast),
+	)
+	app.engine = engine
+
+	// 子命令模式：`flash-monitor-api [flags] backfill -from N -to M` 执行完回填后直接退出，不启动 API 服务
+	if flag.Arg(0) == "backfill" {
+		if err := app.backfillCommand(flag.Args()[1:]); err != nil {
+			logger.Error("backfill failed", "error", err)
+			os.Exit(1)
+		}
+		return
 	}
 
+	// 恢复上次进程退出时未完成的回填任务
+	app.resumeBackfills()
+
 	app.wg.Add(1)
 	go func() {
 		defer app.wg.Done()
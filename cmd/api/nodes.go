@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/rpc"
+)
+
+// nodeConfigs 根据 -rpc-* 参数生成节点配置
+// 指定了 -rpc-config 时从节点配置文件读取，忽略其它 -rpc-* 节点参数；
+// 否则权重、限流与能力参数都是与 -rpc-urls 一一对应的逗号分隔列表，某一项留空表示使用默认值（不限制）
+func (cfg config) nodeConfigs() ([]rpc.NodeConfig, error) {
+	if cfg.rpc.configFile != "" {
+		return loadNodesFile(cfg.rpc.configFile, cfg.rpc.timeout)
+	}
+
+	rawUrls := strings.Split(cfg.rpc.urls, ",")
+
+	weights, err := perNode(cfg.rpc.weights, len(rawUrls), "rpc weights")
+	if err != nil {
+		return nil, err
+	}
+	rps, err := perNode(cfg.rpc.rps, len(rawUrls), "rpc rps")
+	if err != nil {
+		return nil, err
+	}
+	bursts, err := perNode(cfg.rpc.burst, len(rawUrls), "rpc burst")
+	if err != nil {
+		return nil, err
+	}
+	dailyLimits, err := perNode(cfg.rpc.dailyLimit, len(rawUrls), "rpc daily limit")
+	if err != nil {
+		return nil, err
+	}
+	archives, err := perNode(cfg.rpc.archive, len(rawUrls), "rpc archive")
+	if err != nil {
+		return nil, err
+	}
+	traces, err := perNode(cfg.rpc.traces, len(rawUrls), "rpc traces")
+	if err != nil {
+		return nil, err
+	}
+	maxLogRanges, err := perNode(cfg.rpc.maxLogRange, len(rawUrls), "rpc max log range")
+	if err != nil {
+		return nil, err
+	}
+
+	var nodeConfigs []rpc.NodeConfig
+	for i, u := range rawUrls {
+		u = strings.TrimSpace(u)
+		if u == "" {
+			continue
+		}
+
+		// 动态生成节点配置，按照书写顺序决定优先级
+		node := rpc.NodeConfig{
+			Name:     "Node-" + string(rune('A'+i)),
+			URL:      u,
+			Priority: i + 1,
+			Weight:   1,
+			Timeout:  cfg.rpc.timeout,
+		}
+
+		if v := weights[i]; v != "" {
+			node.Weight, err = strconv.Atoi(v)
+			if err != nil || node.Weight < 1 {
+				return nil, fmt.Errorf("invalid rpc weight %q for %s, must be a positive integer", v, node.Name)
+			}
+		}
+		if v := rps[i]; v != "" {
+			node.RateLimit, err = strconv.ParseFloat(v, 64)
+			if err != nil || node.RateLimit < 0 {
+				return nil, fmt.Errorf("invalid rpc rps %q for %s", v, node.Name)
+			}
+		}
+		if v := bursts[i]; v != "" {
+			node.Burst, err = strconv.Atoi(v)
+			if err != nil || node.Burst < 0 {
+				return nil, fmt.Errorf("invalid rpc burst %q for %s", v, node.Name)
+			}
+		}
+		if v := dailyLimits[i]; v != "" {
+			node.DailyLimit, err = strconv.ParseInt(v, 10, 64)
+			if err != nil || node.DailyLimit < 0 {
+				return nil, fmt.Errorf("invalid rpc daily limit %q for %s", v, node.Name)
+			}
+		}
+		if v := archives[i]; v != "" {
+			node.Capabilities.Archive, err = strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rpc archive flag %q for %s, must be true or false", v, node.Name)
+			}
+		}
+		if v := traces[i]; v != "" {
+			node.Capabilities.Traces, err = strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rpc traces flag %q for %s, must be true or false", v, node.Name)
+			}
+		}
+		if v := maxLogRanges[i]; v != "" {
+			node.Capabilities.MaxLogRange, err = strconv.ParseInt(v, 10, 64)
+			if err != nil || node.Capabilities.MaxLogRange < 0 {
+				return nil, fmt.Errorf("invalid rpc max log range %q for %s", v, node.Name)
+			}
+		}
+
+		nodeConfigs = append(nodeConfigs, node)
+	}
+	return nodeConfigs, nil
+}
+
+// perNode 拆分与节点一一对应的逗号分隔参数；未配置时返回 n 个空字符串
+func perNode(raw string, n int, name string) ([]string, error) {
+	if raw == "" {
+		return make([]string, n), nil
+	}
+
+	values := strings.Split(raw, ",")
+	if len(values) != n {
+		return nil, fmt.Errorf("%s must match rpc urls one to one (got %d values for %d urls)", name, len(values), n)
+	}
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return values, nil
+}
+
+// nodeFileEntry 节点配置文件中的一项
+// url、headers 的值与 jwt_secret 支持 ${VAR} 形式引用环境变量，密钥可以不写进文件
+type nodeFileEntry struct {
+	Name        string            `json:"name"`
+	URL         string            `json:"url"`
+	Priority    *int              `json:"priority"`
+	Weight      int               `json:"weight"`
+	Timeout     string            `json:"timeout"`
+	RateLimit   float64           `json:"rate_limit"`
+	Burst       int               `json:"burst"`
+	DailyLimit  int64             `json:"daily_limit"`
+	Archive     bool              `json:"archive"`
+	Traces      bool              `json:"traces"`
+	MaxLogRange int64             `json:"max_log_range"`
+	Headers     map[string]string `json:"headers"`
+	JWTSecret   string            `json:"jwt_secret"` // 十六进制编码的 32 字节 HS256 密钥，与 geth 的 jwtsecret 文件格式相同
+}
+
+// loadNodesFile 读取 JSON 格式的节点配置文件；未填写优先级时按书写顺序排列，未填写超时时使用 defaultTimeout
+func loadNodesFile(path string, defaultTimeout time.Duration) ([]rpc.NodeConfig, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rpc config file: %w", err)
+	}
+
+	var entries []nodeFileEntry
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&entries); err != nil {
+		return nil, fmt.Errorf("parse rpc config file %s: %w", path, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("rpc config file %s defines no nodes", path)
+	}
+
+	names := make(map[string]bool)
+	var nodeConfigs []rpc.NodeConfig
+	for i, entry := range entries {
+		node, err := entry.nodeConfig(i, defaultTimeout, expandEnv)
+		if err != nil {
+			return nil, fmt.Errorf("rpc config file %s: entry %d: %w", path, i, err)
+		}
+		if names[node.Name] {
+			return nil, fmt.Errorf("rpc config file %s: duplicate node name %q", path, node.Name)
+		}
+		names[node.Name] = true
+		nodeConfigs = append(nodeConfigs, node)
+	}
+	return nodeConfigs, nil
+}
+
+// nodeConfig 校验并转换为节点配置；url、headers 的值与 jwt_secret 经 expand 处理，
+// 配置文件传入 expandEnv，管理接口传入 literal，请求体不能读取服务端环境变量
+func (entry nodeFileEntry) nodeConfig(i int, defaultTimeout time.Duration, expand func(string) (string, error)) (rpc.NodeConfig, error) {
+	node := rpc.NodeConfig{
+		Name:       strings.TrimSpace(entry.Name),
+		Priority:   i + 1,
+		Weight:     max(entry.Weight, 1),
+		Timeout:    defaultTimeout,
+		RateLimit:  entry.RateLimit,
+		Burst:      entry.Burst,
+		DailyLimit: entry.DailyLimit,
+		Capabilities: rpc.Capabilities{
+			Archive:     entry.Archive,
+			Traces:      entry.Traces,
+			MaxLogRange: entry.MaxLogRange,
+		},
+	}
+	if node.Name == "" {
+		node.Name = "Node-" + string(rune('A'+i))
+	}
+	if entry.Priority != nil {
+		node.Priority = *entry.Priority
+	}
+
+	var err error
+	if node.URL, err = expand(strings.TrimSpace(entry.URL)); err != nil {
+		return node, fmt.Errorf("url: %w", err)
+	}
+	if !hasRPCScheme(node.URL) {
+		return node, errors.New("url must start with http://, https://, ws:// or wss://")
+	}
+
+	switch {
+	case entry.Weight < 0:
+		return node, errors.New("weight must not be negative")
+	case entry.RateLimit < 0 || entry.Burst < 0 || entry.DailyLimit < 0:
+		return node, errors.New("rate_limit, burst and daily_limit must not be negative")
+	case entry.MaxLogRange < 0:
+		return node, errors.New("max_log_range must not be negative")
+	}
+
+	if entry.Timeout != "" {
+		node.Timeout, err = time.ParseDuration(entry.Timeout)
+		if err != nil || node.Timeout <= 0 {
+			return node, fmt.Errorf("invalid timeout %q, must be a positive duration such as 5s", entry.Timeout)
+		}
+	}
+
+	if len(entry.Headers) > 0 {
+		node.Headers = make(map[string]string, len(entry.Headers))
+		for key, value := range entry.Headers {
+			if node.Headers[key], err = expand(value); err != nil {
+				return node, fmt.Errorf("header %s: %w", key, err)
+			}
+		}
+	}
+
+	if entry.JWTSecret != "" {
+		secret, err := expand(entry.JWTSecret)
+		if err != nil {
+			return node, fmt.Errorf("jwt_secret: %w", err)
+		}
+		node.JWTSecret, err = hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(secret), "0x"))
+		if err != nil || len(node.JWTSecret) != rpc.JWTSecretLength {
+			return node, fmt.Errorf("jwt_secret must be a hex encoded %d-byte key", rpc.JWTSecretLength)
+		}
+		for key := range node.Headers {
+			if strings.EqualFold(key, "Authorization") {
+				return node, errors.New("jwt_secret and an Authorization header cannot be used together")
+			}
+		}
+	}
+	return node, nil
+}
+
+// expandEnv 替换 ${VAR} / $VAR 形式的环境变量引用，引用了未设置的变量时报错，避免带着空密钥启动
+func expandEnv(s string) (string, error) {
+	var missing []string
+	expanded := os.Expand(s, func(key string) string {
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			missing = append(missing, key)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// literal 原样使用管理接口传入的值；包含 $ 时拒绝，环境变量只在节点配置文件中替换
+func literal(s string) (string, error) {
+	if strings.Contains(s, "$") {
+		return "", errors.New("must not contain $, environment variables are only expanded in the rpc config file")
+	}
+	return s, nil
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/zy99978455-otw/flash-monitor/internal/rpc"
+	"github.com/zy99978455-otw/flash-monitor/internal/validator"
+)
+
+// nodeDrainTimeout 摘除节点时最多等待在途请求结束的时间
+const nodeDrainTimeout = 30 * time.Second
+
+// nodeView 节点配置与完整状态的 JSON 视图
+type nodeView struct {
+	Name         string           `json:"name"`
+	URL          string           `json:"url"`
+	Priority     int              `json:"priority"`
+	Weight       int              `json:"weight"`
+	Timeout      string           `json:"timeout"`
+	RateLimit    float64          `json:"rate_limit"`
+	Burst        int              `json:"burst"`
+	DailyLimit   int64            `json:"daily_limit"`
+	Capabilities capabilitiesView `json:"capabilities"`
+	Status       nodeStatusView   `json:"status"`
+}
+
+type capabilitiesView struct {
+	Archive     bool  `json:"archive"`
+	Traces      bool  `json:"traces"`
+	WebSocket   bool  `json:"websocket"`
+	MaxLogRange int64 `json:"max_log_range"`
+}
+
+type nodeStatusView struct {
+	Healthy          bool       `json:"healthy"`
+	LastCheckTime    time.Time  `json:"last_check_time"`
+	LatestBlock      uint64     `json:"latest_block"`
+	BlocksBehind     uint64     `json:"blocks_behind"`
+	ResponseTime     string     `json:"response_time"`
+	ErrorCount       int        `json:"error_count"`
+	SuccessCount     int        `json:"success_count"`
+	Circuit          string     `json:"circuit"`
+	LastError        string     `json:"last_error,omitempty"`
+	QuarantinedUntil *time.Time `json:"quarantined_until,omitempty"`
+	DailyRequests    int64      `json:"daily_requests"`
+	DailyRemaining   int64      `json:"daily_remaining"`
+	RateTokens       float64    `json:"rate_tokens"`
+}
+
+func newNodeView(node *rpc.Node) nodeView {
+	cfg := node.ConfigSnapshot()
+	status := node.StatusSnapshot()
+
+	view := nodeView{
+		Name:       cfg.Name,
+		URL:        cfg.URL,
+		Priority:   cfg.Priority,
+		Weight:     cfg.Weight,
+		Timeout:    cfg.Timeout.String(),
+		RateLimit:  cfg.RateLimit,
+		Burst:      cfg.Burst,
+		DailyLimit: cfg.DailyLimit,
+		Capabilities: capabilitiesView{
+			Archive:     cfg.Capabilities.Archive,
+			Traces:      cfg.Capabilities.Traces,
+			WebSocket:   cfg.Capabilities.WebSocket,
+			MaxLogRange: cfg.Capabilities.MaxLogRange,
+		},
+		Status: nodeStatusView{
+			Healthy:        status.IsHealthy,
+			LastCheckTime:  status.LastCheckTime,
+			LatestBlock:    status.LatestBlock,
+			BlocksBehind:   status.BlocksBehind,
+			ResponseTime:   status.ResponseTime.String(),
+			ErrorCount:     status.ErrorCount,
+			SuccessCount:   status.SuccessCount,
+			Circuit:        status.Circuit,
+			DailyRequests:  status.DailyRequests,
+			DailyRemaining: status.DailyRemaining,
+			RateTokens:     status.RateTokens,
+		},
+	}
+	if status.LastError != nil {
+		view.Status.LastError = status.LastError.Error()
+	}
+	if status.QuarantinedUntil.After(time.Now()) {
+		view.Status.QuarantinedUntil = &status.QuarantinedUntil
+	}
+	return view
+}
+
+// readNodeParam 根据路由参数 :name 查找节点，找不到时已写入 404 响应
+func (app *application) readNodeParam(w http.ResponseWriter, r *http.Request) (*rpc.Node, bool) {
+	name := httprouter.ParamsFromContext(r.Context()).ByName("name")
+
+	node, err := app.nodeManager.Node(name)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+	return node, true
+}
+
+func (app *application) listNodesHandler(w http.ResponseWriter, r *http.Request) {
+	nodes := app.nodeManager.Nodes()
+
+	views := make([]nodeView, len(nodes))
+	for i, node := range nodes {
+		views[i] = newNodeView(node)
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"nodes": views, "best_head": app.nodeManager.BestHead()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showNodeHandler(w http.ResponseWriter, r *http.Request) {
+	node, ok := app.readNodeParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"node": newNodeView(node)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createNodeHandler 运行时新增节点；新节点通过首次健康检查后才会参与请求分配
+// 请求体与节点配置文件中的一项字段相同（包括 headers 与 jwt_secret），校验规则也与配置文件一致，
+// 但不替换 ${VAR}：请求体中的值按字面使用，包含 $ 时拒绝
+func (app *application) createNodeHandler(w http.ResponseWriter, r *http.Request) {
+	var input nodeFileEntry
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(strings.TrimSpace(input.Name) != "", "name", "must be provided")
+	v.Check(len(strings.TrimSpace(input.Name)) <= 64, "name", "must not be more than 64 bytes long")
+	v.Check(strings.TrimSpace(input.URL) != "", "url", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	cfg, err := input.nodeConfig(0, app.config.rpc.timeout, literal)
+	if err != nil {
+		v.AddError("node", err.Error())
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// 未指定优先级时排在现有节点之后
+	if input.Priority == nil {
+		cfg.Priority = 0
+		for _, node := range app.nodeManager.Nodes() {
+			cfg.Priority = max(cfg.Priority, node.ConfigSnapshot().Priority+1)
+		}
+	}
+
+	node, err := app.nodeManager.AddNode(cfg)
+	if err != nil {
+		switch {
+		case errors.Is(err, rpc.ErrDuplicateNode):
+			app.editConflictResponse(w, r, "a node with this name already exists")
+		case errors.Is(err, rpc.ErrChainMismatch):
+			app.editConflictResponse(w, r, err.Error())
+		default:
+			v.AddError("url", err.Error())
+			app.failedValidationResponse(w, r, v.Errors)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", "/v1/admin/nodes/"+url.PathEscape(node.Config.Name))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"node": newNodeView(node)}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateNodeHandler 修改节点的优先级与权重
+func (app *application) updateNodeHandler(w http.ResponseWriter, r *http.Request) {
+	node, ok := app.readNodeParam(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Priority *int `json:"priority"`
+		Weight   *int `json:"weight"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Priority != nil || input.Weight != nil, "priority", "priority or weight must be provided")
+	if input.Weight != nil {
+		v.Check(*input.Weight >= 1, "weight", "must be a positive integer")
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	node, err = app.nodeManager.UpdateNode(node.Config.Name, rpc.NodeUpdate{Priority: input.Priority, Weight: input.Weight})
+	if err != nil {
+		switch {
+		case errors.Is(err, rpc.ErrNodeNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"node": newNodeView(node)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteNodeHandler 摘除节点：不再分配新请求，等待在途请求结束后断开连接
+func (app *application) deleteNodeHandler(w http.ResponseWriter, r *http.Request) {
+	node, ok := app.readNodeParam(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), nodeDrainTimeout)
+	defer cancel()
+
+	err := app.nodeManager.RemoveNode(ctx, node.Config.Name)
+	if err != nil {
+		switch {
+		case errors.Is(err, rpc.ErrNodeNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, rpc.ErrLastNode):
+			app.editConflictResponse(w, r, "cannot remove the last rpc node")
+		case errors.Is(err, rpc.ErrBelowMinNodes):
+			app.editConflictResponse(w, r, err.Error())
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "node successfully removed"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// checkNodeHandler 立即对节点执行一次健康检查
+func (app *application) checkNodeHandler(w http.ResponseWriter, r *http.Request) {
+	node, ok := app.readNodeParam(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := app.nodeManager.CheckNode(node.Config.Name); err != nil {
+		switch {
+		case errors.Is(err, rpc.ErrNodeNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"node": newNodeView(node)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// hasRPCScheme 节点 URL 是否使用受支持的协议
+func hasRPCScheme(u string) bool {
+	for _, scheme := range []string{"http://", "https://", "ws://", "wss://"} {
+		if strings.HasPrefix(strings.ToLower(u), scheme) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testJWTSecret = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+// writeNodesFile 把 content 写入临时的节点配置文件并返回路径
+func writeNodesFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "nodes.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadNodesFileExample(t *testing.T) {
+	t.Setenv("ALCHEMY_KEY", "alchemy-key")
+	t.Setenv("ARCHIVE_API_TOKEN", "archive-token")
+	t.Setenv("NODE_JWT_SECRET", "0x"+testJWTSecret)
+
+	nodes, err := loadNodesFile(filepath.Join("..", "..", "nodes.example.json"), 7*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 3 {
+		t.Fatalf("loaded %d nodes, want 3", len(nodes))
+	}
+
+	alchemy, archive, selfHosted := nodes[0], nodes[1], nodes[2]
+	if alchemy.URL != "https://eth-mainnet.g.alchemy.com/v2/alchemy-key" {
+		t.Errorf("alchemy url = %s, want the key expanded", alchemy.URL)
+	}
+	if alchemy.Weight != 3 || alchemy.Timeout != 5*time.Second || alchemy.RateLimit != 25 || alchemy.Burst != 50 || alchemy.Capabilities.MaxLogRange != 2000 {
+		t.Errorf("alchemy = %+v, want weight 3, timeout 5s, rate 25/50 and max log range 2000", alchemy)
+	}
+	if archive.Headers["Authorization"] != "Bearer archive-token" || !archive.Capabilities.Archive || !archive.Capabilities.Traces || archive.DailyLimit != 100000 {
+		t.Errorf("archive = %+v, want expanded Authorization header, archive/traces and daily limit", archive)
+	}
+	if selfHosted.Weight != 1 || selfHosted.Timeout != 7*time.Second || selfHosted.Priority != 3 {
+		t.Errorf("self-hosted = %+v, want default weight 1 and timeout 7s", selfHosted)
+	}
+	if len(selfHosted.JWTSecret) != 32 || selfHosted.JWTSecret[31] != 0x1f {
+		t.Errorf("self-hosted jwt secret = %x, want the decoded 32-byte key", selfHosted.JWTSecret)
+	}
+}
+
+func TestLoadNodesFileDefaults(t *testing.T) {
+	path := writeNodesFile(t, `[{"url": "http://a"}, {"url": "ws://b", "priority": 0}]`)
+
+	nodes, err := loadNodesFile(path, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 未填写名称与优先级时按书写顺序生成
+	if nodes[0].Name != "Node-A" || nodes[0].Priority != 1 {
+		t.Errorf("first node = %s priority %d, want Node-A priority 1", nodes[0].Name, nodes[0].Priority)
+	}
+	if nodes[1].Name != "Node-B" || nodes[1].Priority != 0 {
+		t.Errorf("second node = %s priority %d, want Node-B with the explicit priority 0", nodes[1].Name, nodes[1].Priority)
+	}
+}
+
+func TestLoadNodesFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"empty list", `[]`, "defines no nodes"},
+		{"unknown field", `[{"url": "http://a", "wieght": 2}]`, "unknown field"},
+		{"bad scheme", `[{"url": "ftp://a"}]`, "url must start with"},
+		{"duplicate name", `[{"name": "a", "url": "http://a"}, {"name": "a", "url": "http://b"}]`, "duplicate node name"},
+		{"negative weight", `[{"url": "http://a", "weight": -1}]`, "weight must not be negative"},
+		{"negative limit", `[{"url": "http://a", "daily_limit": -1}]`, "must not be negative"},
+		{"bad timeout", `[{"url": "http://a", "timeout": "0s"}]`, "invalid timeout"},
+		{"missing env", `[{"url": "http://a/${FLASH_TEST_UNSET_VAR}"}]`, "FLASH_TEST_UNSET_VAR is not set"},
+		{"non-hex jwt secret", `[{"url": "http://a", "jwt_secret": "not-hex"}]`, "jwt_secret must be a hex encoded 32-byte key"},
+		{"short jwt secret", `[{"url": "http://a", "jwt_secret": "0011"}]`, "jwt_secret must be a hex encoded 32-byte key"},
+		{"jwt with authorization", `[{"url": "http://a", "jwt_secret": "` + testJWTSecret + `", "headers": {"authorization": "Bearer x"}}]`, "cannot be used together"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadNodesFile(writeNodesFile(t, tt.content), time.Second)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadNodesFile() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("FLASH_TEST_KEY", "secret")
+	t.Setenv("FLASH_TEST_EMPTY", "")
+
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"https://rpc/${FLASH_TEST_KEY}", "https://rpc/secret", false},
+		{"https://rpc/$FLASH_TEST_KEY", "https://rpc/secret", false},
+		{"https://rpc/plain", "https://rpc/plain", false},
+		{"${FLASH_TEST_EMPTY}", "", false}, // 已设置的空值不是缺失
+		{"${FLASH_TEST_UNSET_VAR}", "", true},
+		{"${FLASH_TEST_KEY}-${FLASH_TEST_UNSET_VAR}", "", true},
+	}
+	for _, tt := range tests {
+		got, err := expandEnv(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("expandEnv(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNodeConfigLiteral(t *testing.T) {
+	t.Setenv("FLASH_TEST_KEY", "secret")
+
+	// 管理接口传入的值不读取服务端环境变量
+	entry := nodeFileEntry{URL: "https://rpc/${FLASH_TEST_KEY}"}
+	if _, err := entry.nodeConfig(0, time.Second, literal); err == nil {
+		t.Error("literal accepted a url referencing an environment variable")
+	}
+	entry = nodeFileEntry{URL: "https://rpc", Headers: map[string]string{"X-Api-Key": "$FLASH_TEST_KEY"}}
+	if _, err := entry.nodeConfig(0, time.Second, literal); err == nil {
+		t.Error("literal accepted a header referencing an environment variable")
+	}
+
+	entry = nodeFileEntry{URL: " https://rpc/key ", JWTSecret: testJWTSecret}
+	node, err := entry.nodeConfig(0, time.Second, literal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.URL != "https://rpc/key" || len(node.JWTSecret) != 32 {
+		t.Errorf("node = %+v, want the literal url and a 32-byte secret", node)
+	}
+}
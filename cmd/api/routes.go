@@ -29,9 +29,25 @@ func (app *application) routes() http.Handler {
 
 	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
 
+	router.HandlerFunc(http.MethodGet, "/v1/nodes", app.nodesHandler)
+
 	router.HandlerFunc(http.MethodGet, "/v1/transactions", app.listTransactionsHandler)
 
+	router.HandlerFunc(http.MethodGet, "/v1/decoded_events", app.listDecodedEventsHandler)
+
 	router.HandlerFunc(http.MethodGet, "/v1/events", app.broker.Handler)
 
+	// 运维接口（需要管理令牌）
+	router.HandlerFunc(http.MethodPost, "/v1/admin/backfills", app.requireAdmin(app.createBackfillHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/admin/backfills/:id", app.requireAdmin(app.showBackfillHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/admin/backfills/:id/resume", app.requireAdmin(app.resumeBackfillHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/admin/nodes", app.requireAdmin(app.listNodesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/admin/nodes", app.requireAdmin(app.createNodeHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/admin/nodes/:name", app.requireAdmin(app.showNodeHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/admin/nodes/:name", app.requireAdmin(app.updateNodeHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/admin/nodes/:name", app.requireAdmin(app.deleteNodeHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/admin/nodes/:name/check", app.requireAdmin(app.checkNodeHandler))
+
 	return app.recoverPanic(router)
 }
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// nodesHandler 公开的节点遥测接口：每个节点的健康度、延迟、区块高度与错误统计，
+// 以及索引器已写入的区块相对链头的落后数。节点 URL 中的 API Key 会被脱敏
+func (app *application) nodesHandler(w http.ResponseWriter, r *http.Request) {
+	nodes := app.nodeManager.Nodes()
+
+	views := make([]nodeView, len(nodes))
+	for i, node := range nodes {
+		views[i] = redactNodeView(newNodeView(node))
+	}
+
+	// 当前承接流量的节点：按选择策略排在最前面的健康节点
+	var primary *string
+	if healthy := app.nodeManager.GetHealthyNodes(); len(healthy) > 0 {
+		primary = &healthy[0].Config.Name
+	}
+
+	latest, err := app.models.BlockTraces.GetLatest()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	chainHead := int64(app.nodeManager.BestHead())
+	indexer := map[string]any{
+		"chain_head":      chainHead,
+		"indexed_height":  nil,
+		"blocks_behind":   nil,
+		"last_block_time": nil,
+	}
+	if latest != nil {
+		indexer["indexed_height"] = latest.BlockNumber
+		indexer["last_block_time"] = latest.BlockTime
+		if chainHead > 0 {
+			indexer["blocks_behind"] = max(chainHead-latest.BlockNumber, 0)
+		}
+	}
+
+	env := envelope{
+		"nodes":   views,
+		"primary": primary,
+		"indexer": indexer,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// redactNodeView 去掉节点 URL 中的路径、查询参数与用户信息（服务商通常把 API Key 放在这些位置），
+// 错误信息中出现的完整 URL 也一并替换
+func redactNodeView(view nodeView) nodeView {
+	redacted := redactURL(view.URL)
+	if view.Status.LastError != "" {
+		view.Status.LastError = strings.ReplaceAll(view.Status.LastError, view.URL, redacted)
+	}
+	view.URL = redacted
+	return view
+}
+
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "[redacted]"
+	}
+
+	redacted := u.Scheme + "://" + u.Host
+	if u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		redacted += "/[redacted]"
+	}
+	return redacted
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/zy99978455-otw/flash-monitor/internal/data"
+	"github.com/zy99978455-otw/flash-monitor/internal/validator"
+)
+
+// loadTokensFile 读取 JSON 格式的监控代币配置文件，并逐条写入 tokens 表
+// 文件中的条目会覆盖数据库中同地址代币的 symbol、decimals 与巨鲸阈值
+func (app *application) loadTokensFile(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read tokens file: %w", err)
+	}
+
+	var tokens []*data.Token
+	if err := json.Unmarshal(raw, &tokens); err != nil {
+		return fmt.Errorf("parse tokens file %s: %w", path, err)
+	}
+
+	for i, token := range tokens {
+		v := validator.New()
+		if data.ValidateToken(v, token); !v.Valid() {
+			return fmt.Errorf("tokens file %s: entry %d is invalid: %v", path, i, v.Errors)
+		}
+
+		// 统一存储为 EIP-55 校验和格式，与 transfer_events.token_address 保持一致
+		token.Address = common.HexToAddress(token.Address).Hex()
+
+		if err := app.models.Tokens.Upsert(token); err != nil {
+			return fmt.Errorf("save token %s: %w", token.Symbol, err)
+		}
+	}
+
+	app.logger.Info("watched tokens loaded from file", "path", path, "count", len(tokens))
+	return nil
+}
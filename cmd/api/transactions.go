@@ -3,6 +3,7 @@ package main
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/zy99978455-otw/flash-monitor/internal/data"
 	"github.com/zy99978455-otw/flash-monitor/internal/validator"
@@ -14,6 +15,8 @@ func (app *application) listTransactionsHandler(w http.ResponseWriter, r *http.R
 	var input struct {
 		FromAddress string
 		ToAddress   string
+		Since       time.Time
+		Until       time.Time
 		data.Filters
 	}
 
@@ -24,12 +27,16 @@ func (app *application) listTransactionsHandler(w http.ResponseWriter, r *http.R
 	input.FromAddress = strings.ToLower(app.readString(qs, "from_address", ""))
 	input.ToAddress = strings.ToLower(app.readString(qs, "to_address", ""))
 
+	// 按出块时间过滤，区间为 [since, until)
+	input.Since = app.readTime(qs, "since", v)
+	input.Until = app.readTime(qs, "until", v)
+
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 
 	// 默认按块号倒序排
 	input.Filters.Sort = app.readString(qs, "sort", "-block_number")
-	input.Filters.SortSafelist = []string{"block_number", "amount", "-block_number", "-amount"}
+	input.Filters.SortSafelist = []string{"block_number", "amount", "block_time", "-block_number", "-amount", "-block_time"}
 
 	// 3. 执行校验
 	if input.FromAddress != "" {
@@ -39,6 +46,10 @@ func (app *application) listTransactionsHandler(w http.ResponseWriter, r *http.R
 		v.Check(validator.IsEthAddress(input.ToAddress), "to_address", "必须是合法的以太坊16进制地址格式")
 	}
 
+	if !input.Since.IsZero() && !input.Until.IsZero() {
+		v.Check(input.Since.Before(input.Until), "until", "must be later than since")
+	}
+
 	// 校验基础的分页与排序规则
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
@@ -46,7 +57,7 @@ func (app *application) listTransactionsHandler(w http.ResponseWriter, r *http.R
 	}
 
 	// 4. 调用升级后的 GetAll
-	events, metadata, err := app.models.TransferEvents.GetAll(input.FromAddress, input.ToAddress, input.Filters)
+	events, metadata, err := app.models.TransferEvents.GetAll(input.FromAddress, input.ToAddress, input.Since, input.Until, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
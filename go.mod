@@ -7,7 +7,11 @@ require (
 	github.com/joho/godotenv v1.5.1
 )
 
-require github.com/lib/pq v1.12.3
+require (
+	github.com/julienschmidt/httprouter v1.3.0
+	github.com/lib/pq v1.12.3
+	golang.org/x/time v0.15.0
+)
 
 require (
 	github.com/cespare/xxhash/v2 v2.3.0 // indirect
@@ -32,12 +36,10 @@ require (
 	github.com/go-ole/go-ole v1.3.0 // indirect
 	github.com/gorilla/websocket v1.5.3 // indirect
 	github.com/holiman/uint256 v1.3.2 // indirect
-	github.com/julienschmidt/httprouter v1.3.0 // indirect
 	github.com/shirou/gopsutil v3.21.4-0.20210419000835-c7a38de76ee5+incompatible // indirect
 	github.com/supranational/blst v0.3.16 // indirect
 	github.com/tklauser/go-sysconf v0.3.12 // indirect
 	github.com/tklauser/numcpus v0.6.1 // indirect
 	golang.org/x/sync v0.19.0 // indirect
 	golang.org/x/sys v0.40.0 // indirect
-	golang.org/x/time v0.15.0 // indirect
 )
@@ -0,0 +1,187 @@
+// Package chaintest 提供内存中的模拟链与可编排故障的 JSON-RPC 节点，
+// 用于在测试中验证重组处理、节点故障转移等不依赖真实网络的行为
+package chaintest
+
+import (
+	"math/big"
+	"slices"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// GenesisTime 创世块时间戳，第 n 个区块的时间为 GenesisTime + 12*n
+const GenesisTime = 1_700_000_000
+
+// 默认的 safe / finalized 标签相对链头的深度
+const (
+	SafeDepth      = 32
+	FinalizedDepth = 64
+)
+
+// USDT 主网 USDT 合约地址，测试中作为默认的监控代币
+var USDT = common.HexToAddress("0xdAC17F958D2ee523a2206206994597C13D831ec7")
+
+// transferTopic ERC20 Transfer(address,address,uint256) 事件签名
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// Transfer 出块时打包进区块的一笔 ERC20 转账
+type Transfer struct {
+	Token  common.Address
+	From   common.Address
+	To     common.Address
+	Amount *big.Int
+}
+
+// block 模拟链上的一个区块
+type block struct {
+	header *types.Header
+	logs   []types.Log
+}
+
+// Chain 内存中的模拟链，从创世块（高度 0）开始出块
+// 多个 Node 可以共享同一条链；Fork 丢弃链头附近的区块后重新出块，模拟重组
+type Chain struct {
+	mu     sync.RWMutex
+	blocks []*block
+	forks  uint64 // 已发生的分叉次数，写入区块头 Extra，使分叉后的同高度区块哈希不同
+}
+
+// NewChain 创建只有创世块的模拟链
+func NewChain() *Chain {
+	c := &Chain{}
+	c.blocks = append(c.blocks, c.newBlock(nil, nil))
+	return c
+}
+
+// Mine 出一个包含 transfers 的新区块，返回其区块头
+func (c *Chain) Mine(transfers ...Transfer) *types.Header {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b := c.newBlock(c.blocks[len(c.blocks)-1].header, transfers)
+	c.blocks = append(c.blocks, b)
+	return types.CopyHeader(b.header)
+}
+
+// MineEmpty 连续出 n 个空区块
+func (c *Chain) MineEmpty(n int) {
+	for range n {
+		c.Mine()
+	}
+}
+
+// Fork 丢弃链头的 depth 个区块，之后 Mine 出的区块与被丢弃的区块哈希不同；
+// 再出 depth 个区块即回到原高度，相当于一次深度为 depth 的重组
+func (c *Chain) Fork(depth int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	depth = min(depth, len(c.blocks)-1) // 创世块不参与重组
+	c.blocks = c.blocks[:len(c.blocks)-depth]
+	c.forks++
+}
+
+// Clone 复制当前链，之后两条链各自出块、分叉互不影响；用于模拟停留在另一条分叉上的节点
+func (c *Chain) Clone() *Chain {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	// 区块本身不可变，共享即可；分叉计数错开，保证两条链之后出的区块不会意外相同
+	return &Chain{blocks: slices.Clone(c.blocks), forks: c.forks + 1<<32}
+}
+
+// Head 当前链头高度
+func (c *Chain) Head() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return uint64(len(c.blocks) - 1)
+}
+
+// Header 返回高度 n 的区块头，不存在时返回 nil
+func (c *Chain) Header(n uint64) *types.Header {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if n >= uint64(len(c.blocks)) {
+		return nil
+	}
+	return types.CopyHeader(c.blocks[n].header)
+}
+
+// logs 返回 [from, to] 内满足过滤条件的日志，addresses 为空表示不限合约
+func (c *Chain) logs(from, to uint64, addresses []common.Address, topics [][]common.Hash) []types.Log {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var out []types.Log
+	for n := from; n <= to && n < uint64(len(c.blocks)); n++ {
+		for _, l := range c.blocks[n].logs {
+			if len(addresses) > 0 && !slices.Contains(addresses, l.Address) {
+				continue
+			}
+			if !matchTopics(l.Topics, topics) {
+				continue
+			}
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// matchTopics 按 eth_getLogs 的语义匹配主题：每个位置为空表示任意值，否则命中其中之一即可
+func matchTopics(have []common.Hash, want [][]common.Hash) bool {
+	if len(want) > len(have) {
+		return false
+	}
+	for i, options := range want {
+		if len(options) > 0 && !slices.Contains(options, have[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// newBlock 在 parent 之上构造区块，parent 为 nil 时构造创世块；调用方需持有写锁
+func (c *Chain) newBlock(parent *types.Header, transfers []Transfer) *block {
+	header := &types.Header{
+		ParentHash:  common.Hash{},
+		UncleHash:   types.EmptyUncleHash,
+		Root:        types.EmptyRootHash,
+		TxHash:      types.EmptyTxsHash,
+		ReceiptHash: types.EmptyReceiptsHash,
+		Difficulty:  new(big.Int),
+		Number:      new(big.Int),
+		GasLimit:    30_000_000,
+		Time:        GenesisTime,
+		Extra:       new(big.Int).SetUint64(c.forks).Bytes(),
+	}
+	if parent != nil {
+		header.ParentHash = parent.Hash()
+		header.Number = new(big.Int).Add(parent.Number, common.Big1)
+		header.Time = GenesisTime + 12*header.Number.Uint64()
+	}
+	hash := header.Hash()
+
+	b := &block{header: header}
+	for i, t := range transfers {
+		b.logs = append(b.logs, types.Log{
+			Address: t.Token,
+			Topics: []common.Hash{
+				transferTopic,
+				common.BytesToHash(t.From.Bytes()),
+				common.BytesToHash(t.To.Bytes()),
+			},
+			Data:           common.BigToHash(t.Amount).Bytes(),
+			BlockNumber:    header.Number.Uint64(),
+			TxHash:         crypto.Keccak256Hash(hash.Bytes(), big.NewInt(int64(i)).Bytes()),
+			TxIndex:        uint(i),
+			BlockHash:      hash,
+			BlockTimestamp: header.Time,
+			Index:          uint(i),
+		})
+	}
+	return b
+}
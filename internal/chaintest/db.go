@@ -0,0 +1,30 @@
+package chaintest
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+)
+
+// OpenDB 连接 FLASH_TEST_DB_DSN 指定的测试库（需已执行全部迁移）并清空索引相关的表，未设置时跳过测试
+// 测试会删除数据，切勿指向开发或生产库
+func OpenDB(t testing.TB) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("FLASH_TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("FLASH_TEST_DB_DSN not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(`TRUNCATE block_traces, transfer_events, decoded_events, tokens, backfill_jobs`); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
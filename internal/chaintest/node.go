@@ -0,0 +1,347 @@
+package chaintest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ChainID 模拟节点默认返回的链 ID
+const ChainID = 1
+
+// errMethodNotFound 模拟节点不支持的方法，对应 JSON-RPC 错误码 -32601
+var errMethodNotFound = errors.New("does not exist/is not available")
+
+// Fault 模拟节点的故障模式
+type Fault int
+
+const (
+	// FaultNone 正常响应
+	FaultNone Fault = iota
+	// FaultRateLimit 返回 HTTP 429 与 Retry-After: 1，模拟服务商限流
+	FaultRateLimit
+	// FaultTimeout 不返回响应，直到客户端取消请求，模拟节点卡死
+	FaultTimeout
+	// FaultServerError 返回 HTTP 503，模拟网关或节点宕机
+	FaultServerError
+)
+
+// Node 基于 httptest.Server 的 JSON-RPC 模拟节点，数据来自它跟随的 Chain
+// 支持 eth_chainId、eth_blockNumber、eth_getBlockByNumber、eth_getLogs 及批量请求；
+// 可随时注入故障或让节点的链头落后，测试结束时自动关闭
+type Node struct {
+	srv  *httptest.Server
+	done chan struct{} // 关闭时释放被 FaultTimeout 挂起的请求
+
+	mu      sync.Mutex
+	chain   *Chain
+	chainID uint64
+	fault   Fault
+	lag     uint64
+	maxLogs uint64
+	delay   time.Duration
+	calls   map[string]int
+	hits    int
+}
+
+// NewNode 启动一个跟随 chain 的模拟节点
+func NewNode(t testing.TB, chain *Chain) *Node {
+	t.Helper()
+
+	n := &Node{
+		done:    make(chan struct{}),
+		chain:   chain,
+		chainID: ChainID,
+		calls:   make(map[string]int),
+	}
+	n.srv = httptest.NewServer(http.HandlerFunc(n.serveHTTP))
+	// Cleanup 后注册先执行：先释放挂起的请求，Close 才不会一直等待
+	t.Cleanup(n.srv.Close)
+	t.Cleanup(func() { close(n.done) })
+	return n
+}
+
+// URL 节点的 HTTP 地址，用作 rpc.NodeConfig.URL
+func (n *Node) URL() string {
+	return n.srv.URL
+}
+
+// SetFault 切换节点的故障模式，FaultNone 恢复正常
+func (n *Node) SetFault(f Fault) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.fault = f
+}
+
+// SetLag 让节点看到的链头比所跟随的链落后 blocks 个区块，高于它的区块查询返回 null
+func (n *Node) SetLag(blocks uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.lag = blocks
+}
+
+// SetChainID 修改 eth_chainId 的返回值，模拟连到其它网络的节点
+func (n *Node) SetChainID(id uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.chainID = id
+}
+
+// SetDelay 让节点在处理每个请求前等待 d，模拟慢节点；客户端取消请求时提前返回
+func (n *Node) SetDelay(d time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.delay = d
+}
+
+// SetMaxLogRange 让节点拒绝跨度超过 blocks 个区块的 eth_getLogs 请求，模拟服务商的范围限制；0 表示不限制
+func (n *Node) SetMaxLogRange(blocks uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.maxLogs = blocks
+}
+
+// Follow 让节点改为跟随另一条链，配合 Chain.Clone 模拟停留在其它分叉上的节点
+func (n *Node) Follow(chain *Chain) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.chain = chain
+}
+
+// Calls 节点收到的 method 调用次数（批量请求按元素计数，故障期间的请求不计入）
+func (n *Node) Calls(method string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.calls[method]
+}
+
+// Requests 节点收到的 HTTP 请求数，包括被注入故障的请求
+func (n *Node) Requests() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.hits
+}
+
+// request JSON-RPC 请求
+type request struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+// response JSON-RPC 响应
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (n *Node) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	fault, delay := n.fault, n.delay
+	n.hits++
+	n.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		case <-n.done:
+			return
+		}
+	}
+
+	switch fault {
+	case FaultRateLimit:
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "too many requests", http.StatusTooManyRequests)
+		return
+	case FaultTimeout:
+		select {
+		case <-r.Context().Done():
+		case <-n.done:
+		}
+		return
+	case FaultServerError:
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	if trimmed := strings.TrimSpace(string(body)); strings.HasPrefix(trimmed, "[") {
+		var reqs []request
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resps := make([]response, len(reqs))
+		for i, req := range reqs {
+			resps[i] = n.handle(req)
+		}
+		json.NewEncoder(w).Encode(resps)
+		return
+	}
+
+	var req request
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(n.handle(req))
+}
+
+// handle 处理单个 JSON-RPC 调用
+func (n *Node) handle(req request) response {
+	n.mu.Lock()
+	chain, chainID, lag, maxLogs := n.chain, n.chainID, n.lag, n.maxLogs
+	n.calls[req.Method]++
+	n.mu.Unlock()
+
+	head := chain.Head() - min(lag, chain.Head())
+	resp := response{JSONRPC: "2.0", ID: req.ID}
+
+	if req.Method == "eth_chainId" {
+		resp.Result = hexutil.Uint64(chainID)
+		return resp
+	}
+	result, err := n.call(chain, head, maxLogs, req)
+	if err != nil {
+		code := -32602
+		if errors.Is(err, errMethodNotFound) {
+			code = -32601
+		}
+		resp.Error = &rpcError{Code: code, Message: err.Error()}
+		return resp
+	}
+	resp.Result = result
+	return resp
+}
+
+func (n *Node) call(chain *Chain, head, maxLogs uint64, req request) (any, error) {
+	switch req.Method {
+	case "eth_blockNumber":
+		return hexutil.Uint64(head), nil
+
+	case "eth_getBlockByNumber":
+		if len(req.Params) == 0 {
+			return nil, fmt.Errorf("missing block number")
+		}
+		number, err := parseBlockNumber(req.Params[0], head)
+		if err != nil {
+			return nil, err
+		}
+		if number > head {
+			return nil, nil
+		}
+		return chain.Header(number), nil
+
+	case "eth_getLogs":
+		if len(req.Params) == 0 {
+			return nil, fmt.Errorf("missing filter")
+		}
+		var filter struct {
+			FromBlock json.RawMessage   `json:"fromBlock"`
+			ToBlock   json.RawMessage   `json:"toBlock"`
+			Address   json.RawMessage   `json:"address"`
+			Topics    []json.RawMessage `json:"topics"`
+		}
+		if err := json.Unmarshal(req.Params[0], &filter); err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+		from, err := parseBlockNumber(filter.FromBlock, head)
+		if err != nil {
+			return nil, err
+		}
+		to, err := parseBlockNumber(filter.ToBlock, head)
+		if err != nil {
+			return nil, err
+		}
+		if maxLogs > 0 && to >= from && to-from+1 > maxLogs {
+			return nil, fmt.Errorf("block range is too large, max %d blocks", maxLogs)
+		}
+		var addresses []common.Address
+		if err := unmarshalOneOrMany(filter.Address, &addresses); err != nil {
+			return nil, fmt.Errorf("invalid address: %w", err)
+		}
+		topics := make([][]common.Hash, len(filter.Topics))
+		for i, raw := range filter.Topics {
+			if err := unmarshalOneOrMany(raw, &topics[i]); err != nil {
+				return nil, fmt.Errorf("invalid topics: %w", err)
+			}
+		}
+		logs := chain.logs(from, min(to, head), addresses, topics)
+		if logs == nil {
+			return []any{}, nil
+		}
+		return logs, nil
+
+	default:
+		return nil, fmt.Errorf("the method %s %w", req.Method, errMethodNotFound)
+	}
+}
+
+// parseBlockNumber 解析区块号参数，标签按节点看到的链头 head 换算；缺省视为 latest
+func parseBlockNumber(raw json.RawMessage, head uint64) (uint64, error) {
+	var s string
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return 0, fmt.Errorf("invalid block number %s", raw)
+		}
+	}
+
+	switch s {
+	case "", "latest", "pending":
+		return head, nil
+	case "safe":
+		return head - min(head, SafeDepth), nil
+	case "finalized":
+		return head - min(head, FinalizedDepth), nil
+	case "earliest":
+		return 0, nil
+	}
+
+	n, err := hexutil.DecodeUint64(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid block number %q: %w", s, err)
+	}
+	return n, nil
+}
+
+// unmarshalOneOrMany 解析既可以是单个值、也可以是数组的过滤参数，null 或缺省时保持为空
+func unmarshalOneOrMany[T any](raw json.RawMessage, out *[]T) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	if raw[0] == '[' {
+		return json.Unmarshal(raw, out)
+	}
+	var v T
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return err
+	}
+	*out = []T{v}
+	return nil
+}
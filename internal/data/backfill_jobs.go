@@ -0,0 +1,138 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/validator"
+)
+
+type BackfillJobModel struct {
+	DB *sql.DB
+}
+
+func ValidateBackfillJob(v *validator.Validator, job *BackfillJob) {
+	v.Check(job.FromBlock >= 0, "from_block", "must not be negative")
+	v.Check(job.ToBlock >= job.FromBlock, "to_block", "must be greater than or equal to from_block")
+}
+
+// Insert 创建一个新的回填任务，游标从 FromBlock 开始
+func (m BackfillJobModel) Insert(job *BackfillJob) error {
+	query := `
+		INSERT INTO backfill_jobs (from_block, to_block, next_block, status)
+		VALUES ($1, $2, $1, $3)
+		RETURNING id, next_block, status, created_at, updated_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, job.FromBlock, job.ToBlock, BackfillPending).Scan(
+		&job.ID,
+		&job.NextBlock,
+		&job.Status,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+	)
+}
+
+// Get 按 ID 读取回填任务，不存在时返回 ErrRecordNotFound
+func (m BackfillJobModel) Get(id int64) (*BackfillJob, error) {
+	query := `
+		SELECT id, from_block, to_block, next_block, status, last_error, created_at, updated_at
+		FROM backfill_jobs
+		WHERE id = $1`
+
+	var job BackfillJob
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&job.ID,
+		&job.FromBlock,
+		&job.ToBlock,
+		&job.NextBlock,
+		&job.Status,
+		&job.LastError,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetUnfinished 返回所有未完成（pending/running）的任务，进程重启后据此恢复
+func (m BackfillJobModel) GetUnfinished() ([]*BackfillJob, error) {
+	query := `
+		SELECT id, from_block, to_block, next_block, status, last_error, created_at, updated_at
+		FROM backfill_jobs
+		WHERE status IN ($1, $2)
+		ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, BackfillPending, BackfillRunning)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*BackfillJob{}
+
+	for rows.Next() {
+		var job BackfillJob
+		err := rows.Scan(
+			&job.ID,
+			&job.FromBlock,
+			&job.ToBlock,
+			&job.NextBlock,
+			&job.Status,
+			&job.LastError,
+			&job.CreatedAt,
+			&job.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, &job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// UpdateProgressTx 在写入事件的同一个事务中推进回填游标，保证“数据落库”与“游标前进”原子一致
+func (m BackfillJobModel) UpdateProgressTx(ctx context.Context, tx *sql.Tx, job *BackfillJob) error {
+	query := `
+		UPDATE backfill_jobs
+		SET next_block = $1, status = $2, updated_at = NOW()
+		WHERE id = $3
+		RETURNING updated_at`
+
+	return tx.QueryRowContext(ctx, query, job.NextBlock, job.Status, job.ID).Scan(&job.UpdatedAt)
+}
+
+// UpdateStatus 更新任务状态与错误信息
+func (m BackfillJobModel) UpdateStatus(job *BackfillJob) error {
+	query := `
+		UPDATE backfill_jobs
+		SET status = $1, last_error = $2, updated_at = NOW()
+		WHERE id = $3
+		RETURNING updated_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, job.Status, job.LastError, job.ID).Scan(&job.UpdatedAt)
+}
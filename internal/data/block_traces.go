@@ -14,11 +14,11 @@ type BlockTraceModel struct {
 // 它是实现引擎“断点续传”和“防区块链分叉回滚”的核心元数据记录器。
 func (m BlockTraceModel) Insert(trace *BlockTrace) error {
 	query := `
-		INSERT INTO block_traces (block_number, block_hash, parent_hash, scan_time)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO block_traces (block_number, block_hash, parent_hash, block_time, scan_time)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id`
 
-	args := []interface{}{trace.BlockNumber, trace.BlockHash, trace.ParentHash, time.Now()}
+	args := []interface{}{trace.BlockNumber, trace.BlockHash, trace.ParentHash, trace.BlockTime, time.Now()}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -28,12 +28,12 @@ func (m BlockTraceModel) Insert(trace *BlockTrace) error {
 
 func (m BlockTraceModel) InsertTx(ctx context.Context, tx *sql.Tx, trace *BlockTrace) error {
 	query := `
-		INSERT INTO block_traces (block_number, block_hash, parent_hash)
-       	VALUES ($1, $2, $3)
+		INSERT INTO block_traces (block_number, block_hash, parent_hash, block_time)
+       	VALUES ($1, $2, $3, $4)
        	ON CONFLICT (block_number) DO NOTHING`
 
 	args := []any{
-		trace.BlockNumber, trace.BlockHash, trace.ParentHash,
+		trace.BlockNumber, trace.BlockHash, trace.ParentHash, trace.BlockTime,
 	}
 
 	_, err := tx.ExecContext(ctx, query, args...)
@@ -45,7 +45,7 @@ func (m BlockTraceModel) InsertTx(ctx context.Context, tx *sql.Tx, trace *BlockT
 // 如果数据库为空（首次启动），将安全地返回 (nil, nil) 而不是报错。
 func (m BlockTraceModel) GetLatest() (*BlockTrace, error) {
 	query := `
-		SELECT id, block_number, block_hash, parent_hash, scan_time 
+		SELECT id, block_number, block_hash, parent_hash, block_time, scan_time 
 		FROM block_traces 
 		ORDER BY block_number DESC 
 		LIMIT 1`
@@ -59,6 +59,7 @@ func (m BlockTraceModel) GetLatest() (*BlockTrace, error) {
 		&trace.BlockNumber,
 		&trace.BlockHash,
 		&trace.ParentHash,
+		&trace.BlockTime,
 		&trace.ScanTime,
 	)
 
@@ -70,3 +71,46 @@ func (m BlockTraceModel) GetLatest() (*BlockTrace, error) {
 	}
 	return &trace, nil
 }
+
+// GetBelow 按区块号倒序返回 blockNumber 以下（不含）的最多 limit 条游标，用于重组时的共同祖先搜索
+func (m BlockTraceModel) GetBelow(blockNumber int64, limit int) ([]*BlockTrace, error) {
+	query := `
+		SELECT id, block_number, block_hash, parent_hash, block_time, scan_time
+		FROM block_traces
+		WHERE block_number < $1
+		ORDER BY block_number DESC
+		LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, blockNumber, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	traces := []*BlockTrace{}
+
+	for rows.Next() {
+		var trace BlockTrace
+		err := rows.Scan(
+			&trace.ID,
+			&trace.BlockNumber,
+			&trace.BlockHash,
+			&trace.ParentHash,
+			&trace.BlockTime,
+			&trace.ScanTime,
+		)
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, &trace)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return traces, nil
+}
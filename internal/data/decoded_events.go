@@ -0,0 +1,96 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type DecodedEventModel struct {
+	DB *sql.DB
+}
+
+// GetAll 按合约地址与事件名查询解码事件，支持分页与排序
+// contractAddress 须为 EIP-55 校验和格式（与写入时一致），直接比较才能用上 idx_decoded_events_contract_event
+func (m DecodedEventModel) GetAll(contractAddress, eventName string, filters Filters) ([]*DecodedEvent, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, tx_hash, log_index, block_number, block_hash, contract_address, event_name, signature, source, payload, block_time, created_at
+		FROM decoded_events
+		WHERE ($1 = '' OR contract_address = $1)
+		AND ($2 = '' OR event_name = $2)
+		ORDER BY %s %s, log_index DESC
+		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []any{contractAddress, eventName, filters.limit(), filters.offset()}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	events := []*DecodedEvent{}
+
+	for rows.Next() {
+		var event DecodedEvent
+		err := rows.Scan(
+			&totalRecords,
+			&event.ID,
+			&event.TxHash,
+			&event.LogIndex,
+			&event.BlockNumber,
+			&event.BlockHash,
+			&event.ContractAddress,
+			&event.EventName,
+			&event.Signature,
+			&event.Source,
+			&event.Payload,
+			&event.BlockTime,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return events, metadata, nil
+}
+
+// InsertTx 在同步事务中写入解码事件，重复扫描时按 (tx_hash, log_index) 去重
+// 合约地址统一存储为 EIP-55 校验和格式
+func (m DecodedEventModel) InsertTx(ctx context.Context, tx *sql.Tx, event *DecodedEvent) error {
+	query := `
+		INSERT INTO decoded_events (tx_hash, log_index, block_number, block_hash, contract_address, event_name, signature, source, payload, block_time)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (tx_hash, log_index) DO NOTHING`
+
+	args := []any{
+		event.TxHash,
+		event.LogIndex,
+		event.BlockNumber,
+		event.BlockHash,
+		common.HexToAddress(event.ContractAddress).Hex(),
+		event.EventName,
+		event.Signature,
+		event.Source,
+		[]byte(event.Payload),
+		event.BlockTime,
+	}
+
+	_, err := tx.ExecContext(ctx, query, args...)
+	return err
+}
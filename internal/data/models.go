@@ -3,9 +3,14 @@ package data
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"time"
 )
 
+// ErrRecordNotFound 查询的记录不存在
+var ErrRecordNotFound = errors.New("record not found")
+
 // BlockTrace 代表 区块扫描轨迹
 // BlockNumber 处理 ·断点续传·
 // BlockHash和ParentHash 处理 ·分叉与回滚·
@@ -14,6 +19,7 @@ type BlockTrace struct {
 	BlockNumber int64     `json:"block_number"`
 	BlockHash   string    `json:"block_hash"`
 	ParentHash  string    `json:"parent_hash"` // 上一个区块的哈希值
+	BlockTime   time.Time `json:"block_time"`  // 区块头中的出块时间
 	ScanTime    time.Time `json:"scan_time"`   //处理完这个区块的现实时间
 }
 
@@ -28,12 +34,71 @@ type TransferEvent struct {
 	ToAddress    string    `json:"to_address"`
 	Amount       string    `json:"amount"` // 使用 string 防止前端和 Go 处理超大金额时精度丢失
 	TokenAddress string    `json:"token_address"`
-	CreatedAt    time.Time `json:"created_at"`
+	BlockTime    time.Time `json:"block_time"` // 出块时间，报表与时间区间查询以它为准
+	CreatedAt    time.Time `json:"created_at"` // 写入数据库的时间
+
+	// 以下字段来自 tokens 表，不落库到 transfer_events
+	Symbol          string `json:"symbol,omitempty"`
+	AmountFormatted string `json:"amount_formatted,omitempty"` // 按 decimals 换算后的可读金额，如 "50000.5"
+}
+
+// DecodedEvent 代表一条按 ABI 文件解码的通用合约事件
+// Payload 为事件参数的 JSON 对象：大整数为十进制字符串，地址与字节为 0x 十六进制
+type DecodedEvent struct {
+	ID              int64           `json:"id"`
+	TxHash          string          `json:"tx_hash"`
+	LogIndex        int             `json:"log_index"`
+	BlockNumber     int64           `json:"block_number"`
+	BlockHash       string          `json:"block_hash"`
+	ContractAddress string          `json:"contract_address"`
+	EventName       string          `json:"event_name"`
+	Signature       string          `json:"signature"` // 如 Approval(address,address,uint256)
+	Source          string          `json:"source"`    // 定义该事件的 ABI 文件名
+	Payload         json.RawMessage `json:"payload"`
+	BlockTime       time.Time       `json:"block_time"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+// Token 代表一个被监控的 ERC20 代币
+// WhaleThreshold 以代币整数单位表示（例如 "50000" 即 50,000 USDT），由引擎结合 Decimals 换算成链上最小单位
+type Token struct {
+	ID             int64      `json:"id"`
+	Address        string     `json:"address"`
+	Symbol         string     `json:"symbol"`
+	Decimals       int        `json:"decimals"`
+	WhaleThreshold string     `json:"whale_threshold"` // 与 Amount 一样使用 string 保留 NUMERIC 精度
+	Name           string     `json:"name"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"` // 最近一次通过 eth_call 核对链上元数据的时间
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// 回填任务状态
+const (
+	BackfillPending   = "pending"
+	BackfillRunning   = "running"
+	BackfillCompleted = "completed"
+	BackfillFailed    = "failed"
+)
+
+// BackfillJob 代表一次历史区块回填任务
+// NextBlock 是回填专属游标：[FromBlock, NextBlock) 已经落库
+type BackfillJob struct {
+	ID        int64     `json:"id"`
+	FromBlock int64     `json:"from_block"`
+	ToBlock   int64     `json:"to_block"`
+	NextBlock int64     `json:"next_block"`
+	Status    string    `json:"status"`
+	LastError string    `json:"last_error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type Models struct {
 	BlockTraces    BlockTraceModel
 	TransferEvents TransferEventModel
+	Tokens         TokenModel
+	BackfillJobs   BackfillJobModel
+	DecodedEvents  DecodedEventModel
 	DB             *sql.DB
 }
 
@@ -41,6 +106,9 @@ func NewModels(db *sql.DB) Models {
 	return Models{
 		BlockTraces:    BlockTraceModel{DB: db},
 		TransferEvents: TransferEventModel{DB: db},
+		Tokens:         TokenModel{DB: db},
+		BackfillJobs:   BackfillJobModel{DB: db},
+		DecodedEvents:  DecodedEventModel{DB: db},
 		DB:             db,
 	}
 }
@@ -58,6 +126,11 @@ func (m Models) RollbackBlock(ctx context.Context, blockNumber int64) error {
 		return err
 	}
 
+	queryDecoded := `DELETE FROM decoded_events WHERE block_number = $1`
+	if _, err = tx.ExecContext(ctx, queryDecoded, blockNumber); err != nil {
+		return err
+	}
+
 	queryTrace := `DELETE FROM block_traces WHERE block_number = $1`
 	if _, err = tx.ExecContext(ctx, queryTrace, blockNumber); err != nil {
 		return err
@@ -65,3 +138,29 @@ func (m Models) RollbackBlock(ctx context.Context, blockNumber int64) error {
 
 	return tx.Commit()
 }
+
+// RollbackAbove 在单个事务中回滚 forkBlock 之上（不含）的全部事件与游标，用于深度重组
+func (m Models) RollbackAbove(ctx context.Context, forkBlock int64) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	queryEvents := `DELETE FROM transfer_events WHERE block_number > $1`
+	if _, err = tx.ExecContext(ctx, queryEvents, forkBlock); err != nil {
+		return err
+	}
+
+	queryDecoded := `DELETE FROM decoded_events WHERE block_number > $1`
+	if _, err = tx.ExecContext(ctx, queryDecoded, forkBlock); err != nil {
+		return err
+	}
+
+	queryTrace := `DELETE FROM block_traces WHERE block_number > $1`
+	if _, err = tx.ExecContext(ctx, queryTrace, forkBlock); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
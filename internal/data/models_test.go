@@ -0,0 +1,120 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/chaintest"
+)
+
+func TestRollbackBlock(t *testing.T) {
+	db := chaintest.OpenDB(t)
+	models := NewModels(db)
+	ctx := context.Background()
+
+	for n := int64(1); n <= 3; n++ {
+		hash := fmt.Sprintf("0x%064x", n)
+		if err := models.BlockTraces.Insert(&BlockTrace{BlockNumber: n, BlockHash: hash, ParentHash: fmt.Sprintf("0x%064x", n-1), BlockTime: time.Unix(chaintest.GenesisTime+12*n, 0)}); err != nil {
+			t.Fatal(err)
+		}
+		if err := models.TransferEvents.Insert(&TransferEvent{
+			TxHash:       fmt.Sprintf("0x%064x", 100+n),
+			BlockNumber:  n,
+			BlockHash:    hash,
+			FromAddress:  "0x1111111111111111111111111111111111111111",
+			ToAddress:    "0x2222222222222222222222222222222222222222",
+			Amount:       "1000000000",
+			TokenAddress: chaintest.USDT.Hex(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = models.DecodedEvents.InsertTx(ctx, tx, &DecodedEvent{
+			TxHash:          fmt.Sprintf("0x%064x", 200+n),
+			BlockNumber:     n,
+			BlockHash:       hash,
+			ContractAddress: chaintest.USDT.Hex(),
+			EventName:       "Approval",
+			Signature:       "Approval(address,address,uint256)",
+			Source:          "erc20.json",
+			Payload:         json.RawMessage(`{}`),
+		})
+		if err != nil {
+			tx.Rollback()
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := models.RollbackBlock(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// 只删除区块 2 的游标与事件，相邻区块不受影响
+	for _, table := range []string{"block_traces", "transfer_events", "decoded_events"} {
+		var blocks []int64
+		rows, err := db.Query(`SELECT block_number FROM ` + table + ` ORDER BY block_number`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rows.Next() {
+			var n int64
+			if err := rows.Scan(&n); err != nil {
+				t.Fatal(err)
+			}
+			blocks = append(blocks, n)
+		}
+		rows.Close()
+
+		if len(blocks) != 2 || blocks[0] != 1 || blocks[1] != 3 {
+			t.Errorf("%s blocks after rollback = %v, want [1 3]", table, blocks)
+		}
+	}
+}
+
+func TestDecodedEventAddressChecksummed(t *testing.T) {
+	db := chaintest.OpenDB(t)
+	models := NewModels(db)
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = models.DecodedEvents.InsertTx(ctx, tx, &DecodedEvent{
+		TxHash:          fmt.Sprintf("0x%064x", 1),
+		BlockNumber:     1,
+		BlockHash:       fmt.Sprintf("0x%064x", 1),
+		ContractAddress: strings.ToLower(chaintest.USDT.Hex()),
+		EventName:       "Approval",
+		Signature:       "Approval(address,address,uint256)",
+		Source:          "erc20.json",
+		Payload:         json.RawMessage(`{}`),
+	})
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 小写地址写入后以校验和格式存储，按校验和格式直接比较即可查到
+	events, _, err := models.DecodedEvents.GetAll(chaintest.USDT.Hex(), "Approval", Filters{Page: 1, PageSize: 20, Sort: "-block_number", SortSafelist: []string{"-block_number"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].ContractAddress != chaintest.USDT.Hex() {
+		t.Fatalf("events = %+v, want one event stored as %s", events, chaintest.USDT.Hex())
+	}
+}
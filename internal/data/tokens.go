@@ -0,0 +1,129 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/validator"
+)
+
+type TokenModel struct {
+	DB *sql.DB
+}
+
+func ValidateToken(v *validator.Validator, token *Token) {
+	v.Check(validator.IsEthAddress(token.Address), "address", "must be a valid hex-encoded Ethereum address")
+	v.Check(token.Symbol != "", "symbol", "must be provided")
+	v.Check(len(token.Symbol) <= 32, "symbol", "must not be more than 32 bytes long")
+	v.Check(token.Decimals >= 0 && token.Decimals <= 77, "decimals", "must be between 0 and 77")
+	v.Check(token.WhaleThreshold != "", "whale_threshold", "must be provided")
+}
+
+// GetAll 返回监控列表中的全部代币，引擎据此构建 eth_getLogs 的过滤条件
+func (m TokenModel) GetAll() ([]*Token, error) {
+	query := `
+		SELECT id, address, symbol, decimals, whale_threshold, name, resolved_at, created_at
+		FROM tokens
+		ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*Token{}
+
+	for rows.Next() {
+		var token Token
+		err := rows.Scan(
+			&token.ID,
+			&token.Address,
+			&token.Symbol,
+			&token.Decimals,
+			&token.WhaleThreshold,
+			&token.Name,
+			&token.ResolvedAt,
+			&token.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, &token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// Upsert 新增代币，地址已存在时覆盖其 symbol、decimals 与巨鲸阈值
+// 同时清空 resolved_at，让元数据解析器重新与链上核对
+func (m TokenModel) Upsert(token *Token) error {
+	query := `
+		INSERT INTO tokens (address, symbol, decimals, whale_threshold)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (address) DO UPDATE
+		SET symbol = EXCLUDED.symbol, decimals = EXCLUDED.decimals, whale_threshold = EXCLUDED.whale_threshold, resolved_at = NULL
+		RETURNING id, created_at`
+
+	args := []any{token.Address, token.Symbol, token.Decimals, token.WhaleThreshold}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&token.ID, &token.CreatedAt)
+}
+
+// UpdateMetadata 写入从链上解析到的 symbol、decimals 与 name，并记录解析时间
+func (m TokenModel) UpdateMetadata(token *Token) error {
+	query := `
+		UPDATE tokens
+		SET symbol = $1, decimals = $2, name = $3, resolved_at = NOW()
+		WHERE id = $4
+		RETURNING resolved_at`
+
+	args := []any{token.Symbol, token.Decimals, token.Name, token.ID}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&token.ResolvedAt)
+}
+
+// FormatUnits 把链上最小单位的整数金额按 decimals 转换为十进制字符串，去掉小数部分末尾的 0
+// 例如 FormatUnits("50000500000", 6) == "50000.5"
+func FormatUnits(amount string, decimals int) string {
+	value, ok := new(big.Int).SetString(amount, 10)
+	if !ok || decimals <= 0 {
+		return amount
+	}
+
+	negative := value.Sign() < 0
+	value.Abs(value)
+
+	digits := value.String()
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+
+	intPart := digits[:len(digits)-decimals]
+	fracPart := strings.TrimRight(digits[len(digits)-decimals:], "0")
+
+	result := intPart
+	if fracPart != "" {
+		result += "." + fracPart
+	}
+	if negative {
+		result = "-" + result
+	}
+	return result
+}
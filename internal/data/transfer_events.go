@@ -29,21 +29,33 @@ func ValidateTransferEvent(v *validator.Validator, event *TransferEvent) {
 }
 
 // GetAll 抓取区块链上的事件日志 (增强版：支持分页、过滤、排序)
-func (m TransferEventModel) GetAll(fromAddress, toAddress string, filters Filters) ([]*TransferEvent, Metadata, error) {
+// since/until 按出块时间过滤，区间为 [since, until)，零值表示不限制
+func (m TransferEventModel) GetAll(fromAddress, toAddress string, since, until time.Time, filters Filters) ([]*TransferEvent, Metadata, error) {
 	// 使用 count(*) OVER() 同时获取总行数
 	// 使用 fmt.Sprintf 注入排序列和方向
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), id, tx_hash, log_index, block_number, block_hash, from_address, to_address, amount, token_address, created_at
-		FROM transfer_events
-		WHERE ($1 = '' OR from_address = $1)
-		AND ($2 = '' OR to_address = $2)
-		ORDER BY %s %s, log_index DESC
-		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+		SELECT count(*) OVER(), e.id, e.tx_hash, e.log_index, e.block_number, e.block_hash, e.from_address, e.to_address, e.amount, e.token_address, e.block_time, e.created_at,
+			COALESCE(t.symbol, ''), t.decimals
+		FROM transfer_events e
+		LEFT JOIN tokens t ON t.address = e.token_address
+		WHERE ($1 = '' OR e.from_address = $1)
+		AND ($2 = '' OR e.to_address = $2)
+		AND ($3::timestamptz IS NULL OR e.block_time >= $3)
+		AND ($4::timestamptz IS NULL OR e.block_time < $4)
+		ORDER BY e.%s %s, e.log_index DESC
+		LIMIT $5 OFFSET $6`, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []any{fromAddress, toAddress, filters.limit(), filters.offset()}
+	args := []any{
+		fromAddress,
+		toAddress,
+		sql.NullTime{Time: since, Valid: !since.IsZero()},
+		sql.NullTime{Time: until, Valid: !until.IsZero()},
+		filters.limit(),
+		filters.offset(),
+	}
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -56,6 +68,7 @@ func (m TransferEventModel) GetAll(fromAddress, toAddress string, filters Filter
 
 	for rows.Next() {
 		var event TransferEvent
+		var decimals sql.NullInt32
 		err := rows.Scan(
 			&totalRecords,
 			&event.ID,
@@ -67,11 +80,17 @@ func (m TransferEventModel) GetAll(fromAddress, toAddress string, filters Filter
 			&event.ToAddress,
 			&event.Amount,
 			&event.TokenAddress,
+			&event.BlockTime,
 			&event.CreatedAt,
+			&event.Symbol,
+			&decimals,
 		)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
+		if decimals.Valid {
+			event.AmountFormatted = FormatUnits(event.Amount, int(decimals.Int32))
+		}
 		events = append(events, &event)
 	}
 
@@ -90,8 +109,8 @@ func (m TransferEventModel) Insert(event *TransferEvent) error {
 	// 使用 ON CONFLICT DO NOTHING 极其重要！
 	// 这样当以太坊出现微小回滚或重复扫描时，相同的 tx_hash + log_index 会被自动忽略，而不会导致程序崩溃
 	query := `
-		INSERT INTO transfer_events (tx_hash, log_index, block_number, block_hash, from_address, to_address, amount, token_address)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO transfer_events (tx_hash, log_index, block_number, block_hash, from_address, to_address, amount, token_address, block_time)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		ON CONFLICT (tx_hash, log_index) DO NOTHING`
 
 	args := []interface{}{
@@ -103,6 +122,7 @@ func (m TransferEventModel) Insert(event *TransferEvent) error {
 		event.ToAddress,
 		event.Amount,
 		event.TokenAddress,
+		event.BlockTime,
 	}
 
 	// 设置 3 秒超时控制
@@ -115,8 +135,8 @@ func (m TransferEventModel) Insert(event *TransferEvent) error {
 
 func (m TransferEventModel) InsertTx(ctx context.Context, tx *sql.Tx, event *TransferEvent) error {
 	query := `
-		INSERT INTO transfer_events (tx_hash, log_index, block_number, block_hash, from_address, to_address, amount, token_address)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO transfer_events (tx_hash, log_index, block_number, block_hash, from_address, to_address, amount, token_address, block_time)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		ON CONFLICT (tx_hash, log_index) DO NOTHING`
 
 	args := []any{
@@ -128,6 +148,7 @@ func (m TransferEventModel) InsertTx(ctx context.Context, tx *sql.Tx, event *Tra
 		event.ToAddress,
 		event.Amount,
 		event.TokenAddress,
+		event.BlockTime,
 	}
 
 	_, err := tx.ExecContext(ctx, query, args...)
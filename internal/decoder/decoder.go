@@ -0,0 +1,265 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/data"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Spec 一个 ABI 配置文件
+// Addresses 为空时，事件对所有监控代币合约生效
+//
+//	{"name": "uniswap-v2-pair", "addresses": ["0x..."], "abi": [ ...标准 ABI JSON... ]}
+//
+// 也可以直接放置 Etherscan 导出的纯 ABI 数组文件，等价于 Addresses 为空
+type Spec struct {
+	Name      string          `json:"name"`
+	Addresses []string        `json:"addresses"`
+	ABI       json.RawMessage `json:"abi"`
+}
+
+// binding 一个已注册的事件及其生效的合约范围
+type binding struct {
+	spec      string
+	event     abi.Event
+	addresses map[common.Address]bool // 为 nil 表示对所有监控代币生效
+}
+
+// Registry 事件解码注册表，按 topic0（事件签名哈希）索引
+type Registry struct {
+	bindings  map[common.Hash][]*binding
+	addresses []common.Address
+}
+
+// NewRegistry 创建一个空的注册表
+func NewRegistry() *Registry {
+	return &Registry{bindings: make(map[common.Hash][]*binding)}
+}
+
+// LoadDir 加载目录下所有 *.json ABI 文件
+func LoadDir(dir string) (*Registry, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	r := NewRegistry()
+	for _, path := range paths {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var spec Spec
+		if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+			spec = Spec{ABI: trimmed}
+		} else if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, fmt.Errorf("parse abi file %s: %w", path, err)
+		}
+		if spec.Name == "" {
+			spec.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+
+		if err := r.Register(spec); err != nil {
+			return nil, fmt.Errorf("abi file %s: %w", path, err)
+		}
+	}
+	return r, nil
+}
+
+// Register 注册一个 Spec 中的全部非匿名事件
+func (r *Registry) Register(spec Spec) error {
+	parsed, err := abi.JSON(bytes.NewReader(spec.ABI))
+	if err != nil {
+		return fmt.Errorf("invalid abi: %w", err)
+	}
+
+	var addresses map[common.Address]bool
+	if len(spec.Addresses) > 0 {
+		addresses = make(map[common.Address]bool, len(spec.Addresses))
+		for _, a := range spec.Addresses {
+			if !common.IsHexAddress(a) {
+				return fmt.Errorf("invalid contract address %q", a)
+			}
+			addr := common.HexToAddress(a)
+			if !addresses[addr] {
+				addresses[addr] = true
+				r.addresses = append(r.addresses, addr)
+			}
+		}
+	}
+
+	for _, event := range parsed.Events {
+		// 匿名事件没有 topic0，无法按签名路由
+		if event.Anonymous {
+			continue
+		}
+		r.bindings[event.ID] = append(r.bindings[event.ID], &binding{
+			spec:      spec.Name,
+			event:     event,
+			addresses: addresses,
+		})
+	}
+	return nil
+}
+
+// Len 返回已注册的事件签名数量
+func (r *Registry) Len() int {
+	return len(r.bindings)
+}
+
+// Topics 返回全部已注册事件的 topic0，用于构建 eth_getLogs 过滤条件
+func (r *Registry) Topics() []common.Hash {
+	topics := make([]common.Hash, 0, len(r.bindings))
+	for id := range r.bindings {
+		topics = append(topics, id)
+	}
+	return topics
+}
+
+// Addresses 返回 ABI 文件中显式声明的合约地址（不含监控代币）
+func (r *Registry) Addresses() []common.Address {
+	addrs := make([]common.Address, len(r.addresses))
+	copy(addrs, r.addresses)
+	return addrs
+}
+
+// Decode 尝试用已注册的事件解码日志，isWatchedToken 用于判断未声明地址的事件是否适用
+// 返回 (nil, nil) 表示没有匹配的事件定义
+func (r *Registry) Decode(vLog types.Log, isWatchedToken func(common.Address) bool) (*data.DecodedEvent, error) {
+	if len(vLog.Topics) == 0 {
+		return nil, nil
+	}
+
+	var lastErr error
+	for _, b := range r.bindings[vLog.Topics[0]] {
+		if b.addresses != nil && !b.addresses[vLog.Address] {
+			continue
+		}
+		if b.addresses == nil && !isWatchedToken(vLog.Address) {
+			continue
+		}
+
+		// 同一签名可能对应不同的 indexed 组合（如 ERC20 与 ERC721 的 Transfer），解码失败时继续尝试下一个
+		payload, err := decodeLog(b.event, vLog)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		return &data.DecodedEvent{
+			TxHash:          vLog.TxHash.Hex(),
+			LogIndex:        int(vLog.Index),
+			BlockNumber:     int64(vLog.BlockNumber),
+			BlockHash:       vLog.BlockHash.Hex(),
+			ContractAddress: vLog.Address.Hex(),
+			EventName:       b.event.Name,
+			Signature:       b.event.Sig,
+			Source:          b.spec,
+			Payload:         payload,
+		}, nil
+	}
+	return nil, lastErr
+}
+
+// decodeLog 解码 indexed（topics）与非 indexed（data）字段，输出 JSON 对象
+func decodeLog(event abi.Event, vLog types.Log) (json.RawMessage, error) {
+	var indexed abi.Arguments
+	for _, arg := range event.Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	if len(vLog.Topics)-1 != len(indexed) {
+		return nil, fmt.Errorf("%s: expected %d indexed topics, got %d", event.Sig, len(indexed), len(vLog.Topics)-1)
+	}
+
+	values := make(map[string]any, len(event.Inputs))
+	if err := abi.ParseTopicsIntoMap(values, indexed, vLog.Topics[1:]); err != nil {
+		return nil, fmt.Errorf("%s: decode topics: %w", event.Sig, err)
+	}
+	if err := event.Inputs.NonIndexed().UnpackIntoMap(values, vLog.Data); err != nil {
+		return nil, fmt.Errorf("%s: decode data: %w", event.Sig, err)
+	}
+
+	// 未命名参数使用位置作为键
+	payload := make(map[string]any, len(values))
+	for i, arg := range event.Inputs {
+		key := arg.Name
+		if key == "" {
+			key = fmt.Sprintf("arg%d", i)
+		}
+		payload[key] = normalize(values[arg.Name])
+	}
+
+	return json.Marshal(payload)
+}
+
+// normalize 把 ABI 解码结果转换为适合 JSONB 存储的值
+// 大整数统一转为十进制字符串（避免前端精度丢失），地址与字节数组转为 0x 十六进制
+func normalize(v any) any {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case *big.Int:
+		return val.String()
+	case common.Address:
+		return val.Hex()
+	case common.Hash:
+		return val.Hex()
+	case []byte:
+		return hexutil.Encode(val)
+	case string, bool:
+		return val
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(rv.Int()).String()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(rv.Uint()).String()
+	case reflect.Array:
+		// bytesN 解码为 [N]byte
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return hexutil.Encode(b)
+		}
+		fallthrough
+	case reflect.Slice:
+		out := make([]any, rv.Len())
+		for i := range out {
+			out[i] = normalize(rv.Index(i).Interface())
+		}
+		return out
+	case reflect.Struct:
+		out := make(map[string]any, rv.NumField())
+		for i := 0; i < rv.NumField(); i++ {
+			// tuple 解码出的匿名结构体在 json tag 中保留了 ABI 原始字段名
+			field := rv.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := field.Tag.Get("json")
+			if name == "" {
+				name = field.Name
+			}
+			out[name] = normalize(rv.Field(i).Interface())
+		}
+		return out
+	}
+	return fmt.Sprint(v)
+}
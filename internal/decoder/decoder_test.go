@@ -0,0 +1,308 @@
+package decoder
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const erc20ABI = `[
+	{"type":"event","name":"Transfer","anonymous":false,"inputs":[
+		{"name":"from","type":"address","indexed":true},
+		{"name":"to","type":"address","indexed":true},
+		{"name":"value","type":"uint256","indexed":false}]},
+	{"type":"event","name":"Approval","anonymous":false,"inputs":[
+		{"name":"owner","type":"address","indexed":true},
+		{"name":"spender","type":"address","indexed":true},
+		{"name":"value","type":"uint256","indexed":false}]},
+	{"type":"event","name":"Hidden","anonymous":true,"inputs":[]}
+]`
+
+const erc721ABI = `[
+	{"type":"event","name":"Transfer","anonymous":false,"inputs":[
+		{"name":"from","type":"address","indexed":true},
+		{"name":"to","type":"address","indexed":true},
+		{"name":"tokenId","type":"uint256","indexed":true}]}
+]`
+
+// pairABI 未命名参数、bytes32 与动态数组
+const pairABI = `[
+	{"type":"event","name":"Sync","anonymous":false,"inputs":[
+		{"name":"","type":"uint112","indexed":false},
+		{"name":"","type":"uint112","indexed":false}]},
+	{"type":"event","name":"Tagged","anonymous":false,"inputs":[
+		{"name":"tag","type":"bytes32","indexed":true},
+		{"name":"amounts","type":"uint256[]","indexed":false},
+		{"name":"memo","type":"bytes","indexed":false},
+		{"name":"ok","type":"bool","indexed":false}]}
+]`
+
+var (
+	token = common.HexToAddress("0xdac17f958d2ee523a2206206994597c13d831ec7")
+	pair  = common.HexToAddress("0x0d4a11d5eeaac28ec3f61d100daf4d40471f1852")
+	alice = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	bob   = common.HexToAddress("0x2222222222222222222222222222222222222222")
+
+	transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+)
+
+func word(n int64) []byte {
+	return common.LeftPadBytes(big.NewInt(n).Bytes(), 32)
+}
+
+func isToken(addr common.Address) bool { return addr == token }
+
+// decodePayload 解码日志并把 JSON 载荷还原为 map 方便比较
+func decodePayload(t *testing.T, r *Registry, vLog types.Log) map[string]any {
+	t.Helper()
+
+	ev, err := r.Decode(vLog, isToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev == nil {
+		t.Fatal("log was not decoded")
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(ev.Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func TestRegister(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register(Spec{Name: "erc20", ABI: json.RawMessage(erc20ABI)}); err != nil {
+		t.Fatal(err)
+	}
+	// 小写地址与重复地址都会被规范化、去重
+	err := r.Register(Spec{Name: "pair", Addresses: []string{"0x0d4a11d5eeaac28ec3f61d100daf4d40471f1852", pair.Hex()}, ABI: json.RawMessage(pairABI)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 匿名事件没有 topic0，不注册
+	if r.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", r.Len())
+	}
+	if addrs := r.Addresses(); len(addrs) != 1 || addrs[0] != pair {
+		t.Errorf("Addresses() = %v, want [%s]", addrs, pair.Hex())
+	}
+
+	if err := r.Register(Spec{ABI: json.RawMessage(`{not json`)}); err == nil {
+		t.Error("invalid abi registered")
+	}
+	if err := r.Register(Spec{Addresses: []string{"0x1234"}, ABI: json.RawMessage(erc20ABI)}); err == nil {
+		t.Error("invalid contract address registered")
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	spec := `{"name": "uniswap-v2-pair", "addresses": ["` + pair.Hex() + `"], "abi": ` + pairABI + `}`
+	if err := os.WriteFile(filepath.Join(dir, "pair.json"), []byte(spec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Etherscan 导出的纯 ABI 数组，名称取自文件名
+	if err := os.WriteFile(filepath.Join(dir, "erc20.json"), []byte(erc20ABI), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", r.Len())
+	}
+
+	ev, err := r.Decode(types.Log{
+		Address: token,
+		Topics:  []common.Hash{transferTopic, common.BytesToHash(alice.Bytes()), common.BytesToHash(bob.Bytes())},
+		Data:    word(1),
+	}, isToken)
+	if err != nil || ev == nil {
+		t.Fatalf("Decode() = %v, %v", ev, err)
+	}
+	if ev.Source != "erc20" {
+		t.Errorf("source = %q, want erc20", ev.Source)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"abi": 1`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadDir(dir); err == nil {
+		t.Error("LoadDir accepted a malformed file")
+	}
+}
+
+func TestDecodeTransfer(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register(Spec{Name: "erc20", ABI: json.RawMessage(erc20ABI)}); err != nil {
+		t.Fatal(err)
+	}
+
+	vLog := types.Log{
+		Address:     token,
+		Topics:      []common.Hash{transferTopic, common.BytesToHash(alice.Bytes()), common.BytesToHash(bob.Bytes())},
+		Data:        common.LeftPadBytes(new(big.Int).Lsh(big.NewInt(1), 100).Bytes(), 32),
+		BlockNumber: 100,
+		TxHash:      common.HexToHash("0xabc"),
+		Index:       7,
+	}
+
+	ev, err := r.Decode(vLog, isToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.EventName != "Transfer" || ev.Signature != "Transfer(address,address,uint256)" || ev.LogIndex != 7 || ev.BlockNumber != 100 {
+		t.Errorf("event = %+v", ev)
+	}
+	if ev.ContractAddress != token.Hex() {
+		t.Errorf("contract address = %s, want checksummed %s", ev.ContractAddress, token.Hex())
+	}
+
+	// 地址为校验和格式，超过 2^53 的整数保存为十进制字符串
+	want := map[string]any{"from": alice.Hex(), "to": bob.Hex(), "value": "1267650600228229401496703205376"}
+	if got := decodePayload(t, r, vLog); !reflect.DeepEqual(got, want) {
+		t.Errorf("payload = %v, want %v", got, want)
+	}
+
+	// 未声明地址的事件只对监控代币生效
+	vLog.Address = pair
+	if ev, err := r.Decode(vLog, isToken); ev != nil || err != nil {
+		t.Errorf("Decode() on an unwatched contract = %v, %v; want nil, nil", ev, err)
+	}
+
+	// 未注册的事件签名
+	vLog.Address = token
+	vLog.Topics[0] = crypto.Keccak256Hash([]byte("Unknown()"))
+	if ev, err := r.Decode(vLog, isToken); ev != nil || err != nil {
+		t.Errorf("Decode() of an unknown event = %v, %v; want nil, nil", ev, err)
+	}
+}
+
+func TestDecodeSharedSignature(t *testing.T) {
+	// ERC20 与 ERC721 的 Transfer 签名相同，靠 indexed 数量区分
+	r := NewRegistry()
+	for _, spec := range []Spec{{Name: "erc20", ABI: json.RawMessage(erc20ABI)}, {Name: "erc721", ABI: json.RawMessage(erc721ABI)}} {
+		if err := r.Register(spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	nft := types.Log{
+		Address: token,
+		Topics:  []common.Hash{transferTopic, common.BytesToHash(alice.Bytes()), common.BytesToHash(bob.Bytes()), common.BigToHash(big.NewInt(42))},
+	}
+	ev, err := r.Decode(nft, isToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Source != "erc721" {
+		t.Errorf("source = %q, want erc721", ev.Source)
+	}
+	if got := decodePayload(t, r, nft)["tokenId"]; got != "42" {
+		t.Errorf("tokenId = %v, want \"42\"", got)
+	}
+
+	// 两种定义都对不上时返回最后一个解码错误
+	nft.Topics = nft.Topics[:2]
+	if _, err := r.Decode(nft, isToken); err == nil {
+		t.Error("log with a wrong number of topics decoded without error")
+	}
+}
+
+func TestDecodeArguments(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register(Spec{Name: "pair", Addresses: []string{pair.Hex()}, ABI: json.RawMessage(pairABI)}); err != nil {
+		t.Fatal(err)
+	}
+	// 显式声明了地址的事件不要求合约在监控列表中
+	notWatched := func(common.Address) bool { return false }
+
+	sync := types.Log{
+		Address: pair,
+		Topics:  []common.Hash{crypto.Keccak256Hash([]byte("Sync(uint112,uint112)"))},
+		Data:    append(word(1000), word(2000)...),
+	}
+	ev, err := r.Decode(sync, notWatched)
+	if err != nil || ev == nil {
+		t.Fatalf("Decode(Sync) = %v, %v", ev, err)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(ev.Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]any{"arg0": "1000", "arg1": "2000"}; !reflect.DeepEqual(payload, want) {
+		t.Errorf("Sync payload = %v, want %v", payload, want)
+	}
+
+	tag := common.HexToHash("0x01")
+	data := append(word(96), word(192)...) // amounts 与 memo 的偏移
+	data = append(data, word(1)...)        // ok
+	data = append(data, word(2)...)        // len(amounts)
+	data = append(data, word(5)...)
+	data = append(data, word(6)...)
+	data = append(data, word(2)...) // len(memo)
+	data = append(data, common.RightPadBytes([]byte{0xbe, 0xef}, 32)...)
+
+	tagged := types.Log{
+		Address: pair,
+		Topics:  []common.Hash{crypto.Keccak256Hash([]byte("Tagged(bytes32,uint256[],bytes,bool)")), tag},
+		Data:    data,
+	}
+	ev, err = r.Decode(tagged, notWatched)
+	if err != nil || ev == nil {
+		t.Fatalf("Decode(Tagged) = %v, %v", ev, err)
+	}
+	payload = nil
+	if err := json.Unmarshal(ev.Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"tag": tag.Hex(), "amounts": []any{"5", "6"}, "memo": "0xbeef", "ok": true}
+	if !reflect.DeepEqual(payload, want) {
+		t.Errorf("Tagged payload = %v, want %v", payload, want)
+	}
+
+	// 同一事件出现在其它合约上时不解码
+	tagged.Address = token
+	if ev, err := r.Decode(tagged, isToken); ev != nil || err != nil {
+		t.Errorf("Decode() on an undeclared contract = %v, %v; want nil, nil", ev, err)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   any
+		want any
+	}{
+		{big.NewInt(-5), "-5"},
+		{uint8(255), "255"},
+		{int32(-7), "-7"},
+		{uint64(1 << 63), "9223372036854775808"},
+		{alice, alice.Hex()},
+		{[]byte{0x01, 0x02}, "0x0102"},
+		{[4]byte{0xde, 0xad, 0xbe, 0xef}, "0xdeadbeef"},
+		{[]common.Address{alice, bob}, []any{alice.Hex(), bob.Hex()}},
+		{struct {
+			Amount *big.Int `json:"amount"`
+			Owner  common.Address
+		}{big.NewInt(3), bob}, map[string]any{"amount": "3", "Owner": bob.Hex()}},
+		{"text", "text"},
+		{nil, nil},
+	}
+
+	for _, tt := range tests {
+		if got := normalize(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("normalize(%v) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/data"
+)
+
+// ErrBackfillAboveHead 回填区间超出了已确认的链高度，这部分应由实时同步负责
+var ErrBackfillAboveHead = errors.New("backfill range exceeds the confirmed chain head")
+
+// Backfill 回填历史区块 [job.NextBlock, job.ToBlock]
+// 复用实时同步的抓取与写库路径，但游标记录在 backfill_jobs 中，不影响 block_traces，也不向 SSE 推送历史事件。
+// 每一批事件与游标在同一事务中提交，进程崩溃后再次调用即可从 NextBlock 续跑。
+func (e *Engine) Backfill(ctx context.Context, job *data.BackfillJob) error {
+	err := e.runBackfill(ctx, job)
+	if err == nil {
+		e.logger.Info("backfill job completed", "job_id", job.ID, "from_block", job.FromBlock, "to_block", job.ToBlock)
+		return nil
+	}
+
+	// 停机导致的中断保持 running 状态，重启后自动恢复
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		e.logger.Info("backfill job interrupted, will resume from cursor", "job_id", job.ID, "next_block", job.NextBlock)
+		return err
+	}
+
+	job.Status = data.BackfillFailed
+	job.LastError = err.Error()
+	if updateErr := e.models.BackfillJobs.UpdateStatus(job); updateErr != nil {
+		e.logger.Error("failed to mark backfill job as failed", "job_id", job.ID, "error", updateErr)
+	}
+	return err
+}
+
+func (e *Engine) runBackfill(ctx context.Context, job *data.BackfillJob) error {
+	if job.NextBlock > job.ToBlock {
+		return nil
+	}
+
+	chainHeight, err := e.getLatestHeight(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get latest height: %w", err)
+	}
+	safeHeight, err := e.getConfirmedHeight(ctx, chainHeight)
+	if err != nil {
+		return fmt.Errorf("failed to get confirmed height: %w", err)
+	}
+	if job.ToBlock > safeHeight {
+		return fmt.Errorf("%w: to_block %d, confirmed head %d", ErrBackfillAboveHead, job.ToBlock, safeHeight)
+	}
+
+	registry, err := e.loadTokenRegistry()
+	if err != nil {
+		return err
+	}
+
+	job.Status = data.BackfillRunning
+	job.LastError = ""
+	if err := e.models.BackfillJobs.UpdateStatus(job); err != nil {
+		return err
+	}
+
+	e.logger.Info("backfill job started", "job_id", job.ID, "next_block", job.NextBlock, "to_block", job.ToBlock)
+
+	for job.NextBlock <= job.ToBlock {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		fromBlock := job.NextBlock
+		toBlock := min(fromBlock+e.logRange.window()-1, job.ToBlock)
+
+		if err := e.backfillBatch(ctx, registry, job, fromBlock, toBlock); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillBatch 回填一个批次：抓取、写入事件、推进游标，三者在同一事务内完成
+func (e *Engine) backfillBatch(ctx context.Context, registry *TokenRegistry, job *data.BackfillJob, fromBlock, toBlock int64) error {
+	fetched, err := e.fetchBatch(ctx, 0, registry, fromBlock, toBlock)
+	if err != nil {
+		return err
+	}
+
+	tx, err := e.models.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := e.dispatchLogs(ctx, tx, fetched); err != nil {
+		return err
+	}
+
+	progress := *job
+	progress.NextBlock = toBlock + 1
+	if progress.NextBlock > progress.ToBlock {
+		progress.Status = data.BackfillCompleted
+	}
+
+	if err := e.models.BackfillJobs.UpdateProgressTx(ctx, tx, &progress); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// 只有事务提交成功后才推进内存中的游标
+	*job = progress
+
+	e.dispatchCommitted(ctx, &CommittedBatch{FromBlock: fromBlock, ToBlock: toBlock, Logs: fetched.logs, Backfill: true})
+
+	transfers, decoded := fetched.counts()
+	e.logger.Info("backfill batch committed", "job_id", job.ID, "from_block", fromBlock, "to_block", toBlock, "whale_events", transfers, "decoded_events", decoded)
+	return nil
+}
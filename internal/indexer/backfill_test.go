@@ -0,0 +1,138 @@
+package indexer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/chaintest"
+	"github.com/zy99978455-otw/flash-monitor/internal/data"
+)
+
+// recorder 记录收到的已提交窗口；onCommit 不为 nil 时在每次提交后调用
+type recorder struct {
+	batches  []*CommittedBatch
+	onCommit func(*CommittedBatch)
+}
+
+func (r *recorder) OnLog(ctx context.Context, tx *sql.Tx, log *Log) error { return nil }
+
+func (r *recorder) OnBlockCommitted(ctx context.Context, batch *CommittedBatch) {
+	r.batches = append(r.batches, batch)
+	if r.onCommit != nil {
+		r.onCommit(batch)
+	}
+}
+
+func (r *recorder) OnReorg(ctx context.Context, forkBlock int64) {}
+
+func TestBackfillResumesFromCursor(t *testing.T) {
+	chain := chaintest.NewChain()
+	whales := map[int64]bool{3: true, 10: true, 20: true, 35: true}
+	for n := int64(1); n <= 40; n++ {
+		if whales[n] {
+			chain.Mine(usdt(5_000))
+		} else {
+			chain.Mine(usdt(10))
+		}
+	}
+
+	e, db := simEngine(t, Config{}, chaintest.NewNode(t, chain))
+	models := data.NewModels(db)
+	rec := &recorder{}
+	e.handlers = append(e.handlers, rec)
+
+	job := &data.BackfillJob{FromBlock: 1, ToBlock: 30}
+	if err := models.BackfillJobs.Insert(job); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一个批次提交后模拟进程停机
+	ctx, cancel := context.WithCancel(context.Background())
+	rec.onCommit = func(*CommittedBatch) { cancel() }
+	if err := e.Backfill(ctx, job); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Backfill() error = %v, want context.Canceled", err)
+	}
+
+	stored, err := models.BackfillJobs.Get(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != data.BackfillRunning || stored.NextBlock != 7 {
+		t.Fatalf("interrupted job = %s next_block %d, want running from 7", stored.Status, stored.NextBlock)
+	}
+
+	// 重启后从库中的游标续跑，已提交的区块不会重复抓取
+	rec.onCommit = nil
+	if err := e.Backfill(context.Background(), stored); err != nil {
+		t.Fatal(err)
+	}
+	stored, err = models.BackfillJobs.Get(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != data.BackfillCompleted || stored.NextBlock != 31 {
+		t.Errorf("finished job = %s next_block %d, want completed at 31", stored.Status, stored.NextBlock)
+	}
+
+	next := int64(1)
+	for _, batch := range rec.batches {
+		if !batch.Backfill {
+			t.Errorf("batch %d-%d not flagged as backfill", batch.FromBlock, batch.ToBlock)
+		}
+		if batch.FromBlock != next {
+			t.Fatalf("batch starts at %d, want %d: blocks skipped or fetched twice", batch.FromBlock, next)
+		}
+		next = batch.ToBlock + 1
+	}
+	if next != 31 {
+		t.Errorf("batches end at block %d, want 30", next-1)
+	}
+
+	// 只写入区间内的巨鲸转账，区块 35 不在回填范围内；回填不写实时同步的游标
+	got := indexedTransfers(t, db)
+	if len(got) != 3 {
+		t.Errorf("transfers = %v, want blocks 3, 10 and 20", got)
+	}
+	for _, n := range []int64{3, 10, 20} {
+		if got[n] != chain.Header(uint64(n)).Hash().Hex() {
+			t.Errorf("transfer in block %d missing or on the wrong hash", n)
+		}
+	}
+	latest, err := models.BlockTraces.GetLatest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest != nil {
+		t.Errorf("backfill wrote block trace %d, want block_traces untouched", latest.BlockNumber)
+	}
+}
+
+func TestBackfillAboveHead(t *testing.T) {
+	chain := chaintest.NewChain()
+	chain.MineEmpty(20)
+
+	e, db := simEngine(t, Config{Confirmations: 5}, chaintest.NewNode(t, chain))
+	models := data.NewModels(db)
+
+	// 已确认高度为 15，16-20 应留给实时同步
+	job := &data.BackfillJob{FromBlock: 10, ToBlock: 16}
+	if err := models.BackfillJobs.Insert(job); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Backfill(context.Background(), job); !errors.Is(err, ErrBackfillAboveHead) {
+		t.Fatalf("Backfill() error = %v, want ErrBackfillAboveHead", err)
+	}
+
+	stored, err := models.BackfillJobs.Get(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != data.BackfillFailed || stored.LastError == "" || stored.NextBlock != 10 {
+		t.Errorf("job = %s next_block %d last_error %q, want failed at 10 with an error", stored.Status, stored.NextBlock, stored.LastError)
+	}
+	if got := indexedTransfers(t, db); len(got) != 0 {
+		t.Errorf("transfers = %v, want none", got)
+	}
+}
@@ -0,0 +1,93 @@
+package indexer
+
+import (
+	"context"
+	"sync"
+)
+
+// 每轮追赶最多预取的窗口数 = 工作协程数 × catchUpWindowsPerWorker，限制乱序完成时内存中暂存的结果
+const catchUpWindowsPerWorker = 4
+
+// catchUpResult 一个窗口的并发抓取结果
+type catchUpResult struct {
+	fromBlock int64
+	toBlock   int64
+	fetched   *batch
+	err       error
+}
+
+// catchUp 落后较多时的追赶模式：
+// 工作协程池并发抓取多个连续窗口（按 slot 分摊到不同健康节点），提交协程再严格按区块顺序逐个提交，
+// 保证 block_traces 游标始终连续。返回本轮最后一个已提交的区块号。
+func (e *Engine) catchUp(ctx context.Context, registry *TokenRegistry, fromBlock, toBlock int64) (int64, error) {
+	workers := e.config.CatchupWorkers
+	window := e.logRange.window()
+
+	// 1. 切分本轮要处理的窗口
+	var results []chan catchUpResult
+	var ranges [][2]int64
+	for start := fromBlock; start <= toBlock && len(ranges) < workers*catchUpWindowsPerWorker; start += window {
+		ranges = append(ranges, [2]int64{start, min(start+window-1, toBlock)})
+		results = append(results, make(chan catchUpResult, 1))
+	}
+
+	e.logger.Info("catching up with worker pool", "from_block", fromBlock, "to_block", ranges[len(ranges)-1][1], "windows", len(ranges), "workers", workers)
+
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 2. 工作协程池并发抓取
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(slot int) {
+			defer wg.Done()
+			for i := range jobs {
+				r := ranges[i]
+				fetched, err := e.fetchBatch(fetchCtx, slot, registry, r[0], r[1])
+				results[i] <- catchUpResult{fromBlock: r[0], toBlock: r[1], fetched: fetched, err: err}
+			}
+		}(w)
+	}
+
+	go func() {
+		defer close(jobs)
+		for i := range ranges {
+			select {
+			case jobs <- i:
+			case <-fetchCtx.Done():
+				return
+			}
+		}
+	}()
+
+	// 3. 严格按顺序提交；任何一个窗口失败都停止后续提交，已提交部分保持连续
+	committed := fromBlock - 1
+	var commitErr error
+	for i := range ranges {
+		var res catchUpResult
+		select {
+		case res = <-results[i]:
+		case <-ctx.Done():
+			commitErr = ctx.Err()
+		}
+		if commitErr != nil {
+			break
+		}
+		if res.err != nil {
+			commitErr = res.err
+			break
+		}
+		if err := e.commitEvents(ctx, res.fromBlock, res.toBlock, toBlock, res.fetched); err != nil {
+			commitErr = err
+			break
+		}
+		committed = res.toBlock
+	}
+
+	cancel()
+	wg.Wait()
+
+	return committed, commitErr
+}
@@ -0,0 +1,73 @@
+package indexer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/chaintest"
+	"github.com/zy99978455-otw/flash-monitor/internal/data"
+)
+
+func TestCatchUpCommitsInOrder(t *testing.T) {
+	chain := chaintest.NewChain()
+	whales := map[int64]bool{2: true, 9: true, 17: true, 33: true}
+	for n := int64(1); n <= 40; n++ {
+		if whales[n] {
+			chain.Mine(usdt(5_000))
+		} else {
+			chain.Mine()
+		}
+	}
+
+	// slot 0 的节点较慢，slot 1 抓取的后续窗口先完成，提交协程必须等待前面的窗口
+	slow, fast := chaintest.NewNode(t, chain), chaintest.NewNode(t, chain)
+	slow.SetDelay(50 * time.Millisecond)
+	e, db := simEngine(t, Config{CatchupWorkers: 2}, slow, fast)
+	rec := &recorder{}
+	e.handlers = append(e.handlers, rec)
+
+	registry, err := e.loadTokenRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	committed, err := e.catchUp(context.Background(), registry, 1, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if committed != 40 {
+		t.Errorf("committed up to %d, want 40", committed)
+	}
+	if fast.Calls("eth_getLogs") == 0 {
+		t.Error("no windows were fetched from the second node")
+	}
+
+	next := int64(1)
+	for _, batch := range rec.batches {
+		if batch.FromBlock != next {
+			t.Fatalf("batch %d-%d committed after block %d: windows committed out of order", batch.FromBlock, batch.ToBlock, next-1)
+		}
+		next = batch.ToBlock + 1
+	}
+	if next != 41 {
+		t.Errorf("batches end at block %d, want 40", next-1)
+	}
+
+	got := indexedTransfers(t, db)
+	if len(got) != len(whales) {
+		t.Errorf("transfers = %v, want blocks 2, 9, 17 and 33", got)
+	}
+	for n := range whales {
+		if got[n] != chain.Header(uint64(n)).Hash().Hex() {
+			t.Errorf("transfer in block %d missing or on the wrong hash", n)
+		}
+	}
+
+	latest, err := data.NewModels(db).BlockTraces.GetLatest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest == nil || latest.BlockNumber != 40 || latest.BlockHash != chain.Header(40).Hash().Hex() {
+		t.Errorf("latest trace = %v, want canonical block 40", latest)
+	}
+}
@@ -2,76 +2,246 @@ package indexer
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math/big"
+	"slices"
 	"time"
 
 	"github.com/zy99978455-otw/flash-monitor/internal/data"
+	"github.com/zy99978455-otw/flash-monitor/internal/decoder"
 	"github.com/zy99978455-otw/flash-monitor/internal/rpc"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
 )
 
+// ErrNoWatchedTokens 监控代币列表为空、ABI 文件也没有声明合约地址时返回，防止空地址过滤条件抓取全网日志
+var ErrNoWatchedTokens = errors.New("no tokens or abi contract addresses configured to watch")
+
+// USDT 太活跃了，50 个块可能超过 10,000 条记录（Infura 的限制）
+// 初始窗口保持 6 个块，之后由 logRangeSizer 根据响应大小自适应调整
+const initialLogRange int64 = 6
+
+// pollInterval 没有 newHeads 订阅时的轮询周期
+const pollInterval = 12 * time.Second
+
+// defaultMaxReorgDepth 默认允许自动处理的重组深度；主网 PoS 之后超过 2 个 epoch 的重组意味着严重事故
+const defaultMaxReorgDepth int64 = 64
+
+// defaultMaxLogRange 自适应窗口的默认上限
+const defaultMaxLogRange int64 = 2000
+
+// transferSigHash ERC20 Transfer 事件的签名 Hash
+var transferSigHash = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// 节点支持的区块标签（EIP-1898 / The Merge 之后的 safe 与 finalized）
+const (
+	FinalityLatest    = "latest"
+	FinalitySafe      = "safe"
+	FinalityFinalized = "finalized"
+)
+
+// Config 抓取引擎的可调参数
+type Config struct {
+	// Confirmations 确认深度：只有距离链头至少 N 个块的区块才会被写入数据库并推送
+	Confirmations int64
+	// FinalityTag 为 safe/finalized 时，改为以节点返回的对应区块作为同步上限（此时忽略 Confirmations）
+	FinalityTag string
+	// MaxLogRange 单次 eth_getLogs 的最大区块跨度，为 0 时使用默认值
+	MaxLogRange int64
+	// CatchupWorkers 追赶模式下并发抓取的协程数，小于等于 1 时始终串行同步
+	CatchupWorkers int
+	// MaxReorgDepth 允许自动回滚的最大重组深度，超过时引擎停机告警，为 0 时使用默认值
+	MaxReorgDepth int64
+	// Decoders ABI 文件注册的通用事件解码器，为 nil 时只处理 ERC20 Transfer
+	Decoders *decoder.Registry
+	// Quorum 重组检测与写入游标所用的区块哈希至少需要多少个节点一致，小于等于 1 时只信任单个节点
+	Quorum int
+}
+
+// Validate 校验引擎配置
+func (c Config) Validate() error {
+	if c.Confirmations < 0 {
+		return errors.New("confirmations must not be negative")
+	}
+	if c.MaxLogRange < 0 {
+		return errors.New("max log range must not be negative")
+	}
+	if c.MaxReorgDepth < 0 {
+		return errors.New("max reorg depth must not be negative")
+	}
+	if c.CatchupWorkers < 0 {
+		return errors.New("catch-up workers must not be negative")
+	}
+	if c.Quorum < 0 {
+		return errors.New("quorum must not be negative")
+	}
+	switch c.FinalityTag {
+	case "", FinalityLatest, FinalitySafe, FinalityFinalized:
+		return nil
+	default:
+		return fmt.Errorf("unsupported finality tag %q (expected latest, safe or finalized)", c.FinalityTag)
+	}
+}
+
 // Engine 抓取器的核心结构体
 type Engine struct {
 	nodeManager *rpc.Manager //智能连接池
 	//client      *ethclient.Client
 	models data.Models
 	logger *slog.Logger
-	events chan *data.TransferEvent
+	config Config
+
+	handlers []Handler // 按注册顺序分发的事件处理器
+
+	logRange *logRangeSizer    // eth_getLogs 自适应窗口
+	decoders *decoder.Registry // 通用事件解码注册表
 }
 
 // NewEngine 初始化并返回一个新的抓取引擎
-// 纯依赖注入，不再返回 error，因为网络连接在 main.go 已经处理好了
-func NewEngine(manager *rpc.Manager, models data.Models, logger *slog.Logger, events chan *data.TransferEvent) *Engine {
+// 纯依赖注入，不再返回 error，因为网络连接在 main.go 已经处理好了；
+// handlers 按顺序接收每个窗口的日志与提交通知，通常至少包含一个 PostgresWriter
+func NewEngine(manager *rpc.Manager, models data.Models, logger *slog.Logger, cfg Config, handlers ...Handler) *Engine {
+	if cfg.MaxLogRange == 0 {
+		cfg.MaxLogRange = defaultMaxLogRange
+	}
+	if cfg.MaxReorgDepth == 0 {
+		cfg.MaxReorgDepth = defaultMaxReorgDepth
+	}
+	decoders := cfg.Decoders
+	if decoders == nil {
+		decoders = decoder.NewRegistry()
+	}
+
 	return &Engine{
 		nodeManager: manager,
 		models:      models,
 		logger:      logger,
-		events:      events,
+		config:      cfg,
+		handlers:    handlers,
+		logRange:    newLogRangeSizer(initialLogRange, cfg.MaxLogRange),
+		decoders:    decoders,
 	}
 }
 
-// Start 启动后台抓取任务 (死循环轮询)
+// Start 启动后台抓取任务
+// 优先通过 ws/wss 节点的 newHeads 订阅在新区块到达时立即同步；
+// 没有可用订阅或订阅断开时退回 12 秒轮询，并在每个轮询周期尝试重新订阅
 func (e *Engine) Start(ctx context.Context) {
 	e.logger.Info("Starting web3 indexer Engine...")
 
-	ticker := time.NewTicker(12 * time.Second) // 以太坊出块大概 12 秒
+	ticker := time.NewTicker(pollInterval) // 以太坊出块大概 12 秒
 	defer ticker.Stop()
 
-	if err := e.syncBlocks(ctx); err != nil {
-		e.logger.Error("failed to sync blocks", "error", err)
+	heads := make(chan *types.Header, 16)
+	sub := e.subscribeNewHeads(ctx, heads)
+	defer func() {
+		if sub != nil {
+			sub.Unsubscribe()
+		}
+	}()
+	lastHead := time.Now()
+
+	// 先与链上核对监控代币的 symbol/decimals/name，巨鲸阈值换算依赖正确的 decimals
+	if err := e.ResolveTokenMetadata(ctx); err != nil {
+		e.logger.Error("failed to resolve token metadata", "error", err)
+	}
+
+	if !e.runSync(ctx) {
+		return
 	}
 
 	for {
-		// 监听两个通道
+		// 订阅断开后 subErr 为 nil，对应 case 永远不会被选中
+		var subErr <-chan error
+		if sub != nil {
+			subErr = sub.Err()
+		}
+
 		select {
 		case <-ctx.Done(): //外部取消信号，优雅停机
 			e.logger.Info("indexer engine gracefully shutting down...")
 			return
+
+		case head := <-heads: //新区块到达
+			// 同步期间可能堆积了多个区块头，只需要按最新的一次同步
+			for len(heads) > 0 {
+				head = <-heads
+			}
+			lastHead = time.Now()
+			e.logger.Debug("new head received", "block", head.Number)
+
+			if !e.runSync(ctx) {
+				return
+			}
+
+		case err := <-subErr: //订阅断开，退回轮询
+			e.logger.Warn("new heads subscription dropped, falling back to polling", "error", err)
+			sub = nil
+
 		case <-ticker.C: //定时器触发信号
 			if ctx.Err() != nil {
 				e.logger.Info("indexer engine gracefully shutting down...")
 				return
 			}
 
-			if err := e.syncBlocks(ctx); err != nil {
-
-				if err == context.Canceled || err == context.DeadlineExceeded {
-					return
+			if sub != nil {
+				// 订阅正常时轮询只做兜底：长时间收不到区块头说明连接已静默失效
+				if time.Since(lastHead) < 2*pollInterval {
+					continue
 				}
+				e.logger.Warn("no new heads received recently, resubscribing", "since", time.Since(lastHead))
+				sub.Unsubscribe()
+			}
 
-				e.logger.Error("failed to sync blocks in current tick", "error", err)
+			sub = e.subscribeNewHeads(ctx, heads)
+			lastHead = time.Now()
+
+			if !e.runSync(ctx) {
+				return
 			}
 		}
 	}
 }
 
+// subscribeNewHeads 尝试订阅 newHeads，失败时返回 nil 表示使用轮询模式
+func (e *Engine) subscribeNewHeads(ctx context.Context, heads chan<- *types.Header) ethereum.Subscription {
+	sub, err := e.nodeManager.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		if !errors.Is(err, rpc.ErrNoSubscriptionNodes) {
+			e.logger.Warn("new heads subscription unavailable, polling instead", "error", err)
+		}
+		return nil
+	}
+	e.logger.Info("following chain head via newHeads subscription")
+	return sub
+}
+
+// runSync 执行一次同步并记录错误，返回 false 表示引擎应退出（上下文已取消或检测到超深重组）
+func (e *Engine) runSync(ctx context.Context) bool {
+	if err := e.syncBlocks(ctx); err != nil {
+		// 只有引擎自身的上下文被取消才退出，单次 RPC 超时不应让引擎停止
+		if ctx.Err() != nil {
+			return false
+		}
+
+		if errors.Is(err, ErrReorgTooDeep) {
+			e.logger.Error("🚨 ALERT: reorg deeper than the configured limit, indexer engine halted; manual intervention required", "error", err)
+			return false
+		}
+
+		e.logger.Error("failed to sync blocks in current tick", "error", err)
+	}
+	return true
+}
+
 // syncBlocks 是抓取引擎的核心同步处理器。
 
 func (e *Engine) syncBlocks(ctx context.Context) error {
@@ -81,36 +251,15 @@ func (e *Engine) syncBlocks(ctx context.Context) error {
 		return fmt.Errorf("failed to get latest height: %w", err)
 	}
 
-	// 1. 链重组（Reorg）循环检测与回滚
-	for {
-		latestTrace, err := e.models.BlockTraces.GetLatest()
-		if err != nil {
-			return err
-		}
-		if latestTrace == nil {
-			break //冷启动
-		}
-		// [V2升级] 自动重试获取区块头
-		rpcHeader, err := e.getHeaderByNumber(ctx, latestTrace.BlockNumber)
-		if err != nil {
-			return fmt.Errorf("failed to fetch header for reorg check: %w", err)
-		}
-
-		if rpcHeader.Hash().Hex() == latestTrace.BlockHash {
-			break //祖先一致，未分叉
-		}
-
-		e.logger.Warn("Chain reorg detected! Initiating database rollback...",
-			"blockNumber", latestTrace.BlockNumber,
-			"db_Hash", latestTrace.BlockHash,
-			"canonical_rpc_hash", rpcHeader.Hash().Hex(),
-		)
+	// 只同步到已确认的高度，未达到确认深度的区块留给下一轮，下游永远看不到可能被回滚的事件
+	safeHeight, err := e.getConfirmedHeight(ctx, chainHeight)
+	if err != nil {
+		return fmt.Errorf("failed to get confirmed height: %w", err)
+	}
 
-		err = e.models.RollbackBlock(ctx, latestTrace.BlockNumber)
-		if err != nil {
-			return fmt.Errorf("error rolling back database block: %w", err)
-		}
-		e.logger.Info("Successfully rolled back single block state", "blockNumber", latestTrace.BlockNumber)
+	// 1. 链重组（Reorg）检测：找到共同祖先并一次性回滚其上的全部数据
+	if err := e.handleReorg(ctx); err != nil {
+		return err
 	}
 
 	var dbHeight int64 = 0
@@ -122,164 +271,418 @@ func (e *Engine) syncBlocks(ctx context.Context) error {
 	if latestTrace != nil {
 		dbHeight = latestTrace.BlockNumber
 	} else {
-		dbHeight = chainHeight - 5 //避免链重组织（reorg）导致数据错误
+		dbHeight = safeHeight - 5 //避免链重组织（reorg）导致数据错误
 	}
 
-	if dbHeight < chainHeight {
-		fromBlock := dbHeight + 1 //下一个未同步块
-		toBlock := chainHeight    //当前链高度
+	if dbHeight >= safeHeight {
+		return nil
+	}
 
-		// USDT 太活跃了，50 个块可能超过 10,000 条记录（Infura 的限制）
-		// 我们把步长缩小到 5 个块，确保请求不会过大
-		if toBlock-fromBlock > 5 {
-			toBlock = fromBlock + 5
-		}
+	// 每轮同步重新加载代币注册表，新增的监控代币无需重启即可生效
+	registry, err := e.loadTokenRegistry()
+	if err != nil {
+		return err
+	}
 
-		e.logger.Info("fetching logs from ethereum node", "from_block", fromBlock, "to", toBlock, "chain_head", chainHeight)
+	// 落后时连续推进多个窗口直到追平，而不是每个 tick 只走一步
+	for fromBlock := dbHeight + 1; fromBlock <= safeHeight; { //下一个未同步块
+		// 落后超过两个窗口时切换到并发追赶模式
+		if e.config.CatchupWorkers > 1 && safeHeight-fromBlock+1 > 2*e.logRange.window() {
+			committed, err := e.catchUp(ctx, registry, fromBlock, safeHeight)
+			if err != nil {
+				return err
+			}
+			fromBlock = committed + 1
+			continue
+		}
 
-		// 定义 ERC20 Transfer 的签名 Hash
-		transferSigHash := crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+		toBlock := min(fromBlock+e.logRange.window()-1, safeHeight)
 
-		// 定义 USDT 的主网官方合约地址
-		usdtAddress := common.HexToAddress("0xdAC17F958D2ee523a2206206994597C13D831ec7")
+		e.logger.Info("fetching logs from ethereum node", "from_block", fromBlock, "to", toBlock, "chain_head", chainHeight, "confirmed_head", safeHeight, "tokens", registry.Len())
 
-		// 把 usdtAddress 放进 Addresses 过滤条件里
-		query := ethereum.FilterQuery{
-			FromBlock: big.NewInt(fromBlock),
-			ToBlock:   big.NewInt(toBlock),
-			Addresses: []common.Address{usdtAddress}, // 👈 雷达锁定 USDT
-			Topics: [][]common.Hash{
-				{transferSigHash},
-			},
+		if err := e.commitWindow(ctx, registry, fromBlock, toBlock, safeHeight); err != nil {
+			return err
 		}
+		fromBlock = toBlock + 1
+	}
+	return nil
+}
 
-		// [V2升级] 带有熔断容灾的日志抓取
-		logs, err := e.fetchLogs(ctx, query)
-		if err != nil {
-			return err
+// commitWindow 抓取并原子提交 [fromBlock, toBlock] 窗口，提交成功后推送事件
+func (e *Engine) commitWindow(ctx context.Context, registry *TokenRegistry, fromBlock, toBlock, chainHeight int64) error {
+	// [V2升级] 带有熔断容灾的日志抓取
+	fetched, err := e.fetchBatch(ctx, hedgedSlot, registry, fromBlock, toBlock)
+	if err != nil {
+		return err
+	}
+
+	return e.commitEvents(ctx, fromBlock, toBlock, chainHeight, fetched)
+}
+
+// commitEvents 在一个事务内把已抓取的日志交给处理器并写入 [fromBlock, toBlock] 的区块游标，提交成功后通知处理器
+// 距离链头 MaxReorgDepth 以内的区块逐块记录游标，重组检测才能覆盖窗口中间的每一个区块；
+// 更早的区块只记录窗口末尾，避免追赶历史时为每个区块多发一次 RPC
+func (e *Engine) commitEvents(ctx context.Context, fromBlock, toBlock, chainHeight int64, fetched *batch) error {
+	if ctx.Err() != nil {
+		e.logger.Info("sync canceled before db transaction, aborting")
+		return ctx.Err()
+	}
+
+	// [V2升级] 批量获取需要记录游标的区块头（一次往返，带重试与故障转移）
+	traceFrom := max(fromBlock, chainHeight-e.config.MaxReorgDepth+1)
+	traceFrom = min(traceFrom, toBlock) // 至少记录窗口末尾
+
+	var numbers []int64
+	for blockNumber := traceFrom; blockNumber <= toBlock; blockNumber++ {
+		numbers = append(numbers, blockNumber)
+	}
+	headers, err := e.getCanonicalHeaders(ctx, numbers)
+	if err != nil {
+		return fmt.Errorf("failed to fetch block headers %d-%d: %w", traceFrom, toBlock, err)
+	}
+
+	var traces []*data.BlockTrace
+	for _, blockNumber := range numbers {
+		header := headers[blockNumber]
+		traces = append(traces, &data.BlockTrace{
+			BlockNumber: blockNumber,
+			BlockHash:   header.Hash().Hex(),
+			ParentHash:  header.ParentHash.Hex(),
+			BlockTime:   blockTime(header.Time),
+		})
+	}
+
+	// 2. 数据库原子事务开启
+	tx, err := e.models.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := e.dispatchLogs(ctx, tx, fetched); err != nil {
+		return err
+	}
+
+	// 更新区块游标
+	for _, trace := range traces {
+		if traceErr := e.models.BlockTraces.InsertTx(ctx, tx, trace); traceErr != nil {
+			e.logger.Error("failed to update block trace cursor", "block_number", trace.BlockNumber, "error", traceErr)
+			return traceErr
 		}
+	}
 
-		if ctx.Err() != nil {
-			e.logger.Info("sync canceled before db transaction, aborting")
-			return ctx.Err()
+	// 3.事务提交
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	e.dispatchCommitted(ctx, &CommittedBatch{FromBlock: fromBlock, ToBlock: toBlock, Logs: fetched.logs})
+	return nil
+}
+
+// batch 一个区块窗口内抓取并解析出的全部日志，按链上顺序排列
+type batch struct {
+	logs []*Log
+}
+
+// counts 返回窗口内巨鲸转账与解码事件的数量
+func (b *batch) counts() (transfers, decoded int) {
+	for _, l := range b.logs {
+		if l.Transfer != nil {
+			transfers++
+		}
+		if l.Decoded != nil {
+			decoded++
 		}
+	}
+	return transfers, decoded
+}
 
-		e.logger.Info("found USDT Transfer logs in current batch", "logs_count", len(logs))
+// fetchBatch 抓取 [fromBlock, toBlock] 内监控合约的日志，解析出巨鲸转账与 ABI 注册的通用事件
+// 只做网络请求与内存解析，不触碰数据库，实时同步与历史回填共用这一条路径。
+// slot 决定优先使用哪个健康节点，并发抓取时用来分摊负载，串行调用传 0 即可；
+// 实时同步传 hedgedSlot，改为对冲请求以压低尾延迟
+func (e *Engine) fetchBatch(ctx context.Context, slot int, registry *TokenRegistry, fromBlock, toBlock int64) (*batch, error) {
+	ctx = e.historical(ctx, fromBlock)
+
+	// 一个 FilterQuery 覆盖所有监控代币、ABI 文件声明的合约以及全部已注册的事件签名
+	query := ethereum.FilterQuery{
+		FromBlock: big.NewInt(fromBlock),
+		ToBlock:   big.NewInt(toBlock),
+		Addresses: e.watchedAddresses(registry),
+		Topics: [][]common.Hash{
+			uniqueHashes([]common.Hash{transferSigHash}, e.decoders.Topics()),
+		},
+	}
+
+	logs, err := e.fetchLogs(ctx, slot, query)
+	if err != nil {
+		return nil, err
+	}
 
-		// 2. 数据库原子事务开启
-		tx, err := e.models.DB.BeginTx(ctx, nil)
+	e.logger.Info("found logs in current batch", "logs_count", len(logs))
+
+	result := &batch{}
+
+	// 遍历事件并解析
+	for _, vLog := range logs {
+		l := &Log{Raw: vLog, Transfer: decodeTransfer(registry, vLog)}
+
+		decoded, err := e.decoders.Decode(vLog, registry.Contains)
 		if err != nil {
-			return err
+			// 单条日志与 ABI 定义不符不应阻塞同步，记录后跳过
+			e.logger.Warn("failed to decode log", "tx_hash", vLog.TxHash.Hex(), "log_index", vLog.Index, "error", err)
+		} else if decoded != nil {
+			decoded.BlockTime = blockTime(vLog.BlockTimestamp)
+			l.Decoded = decoded
 		}
-		defer tx.Rollback()
 
-		var pendingPushEvents []*data.TransferEvent
+		result.logs = append(result.logs, l)
+	}
 
-		// 遍历事件并解析
-		for _, vLog := range logs {
+	if err := e.fillBlockTimes(ctx, result); err != nil {
+		return nil, err
+	}
 
-			// 🛑 核心拦截：如果插入一半按了 Ctrl+C，立刻报错退出，触发 tx.Rollback()
-			if ctx.Err() != nil {
-				e.logger.Warn("sync canceled during db insert, aborting current batch")
-				return ctx.Err()
-			}
+	return result, nil
+}
 
-			if len(vLog.Topics) != 3 {
-				continue
-			}
+// decodeTransfer 解析 ERC20 Transfer 日志，不是监控代币的 Transfer 或未达到巨鲸阈值时返回 nil
+func decodeTransfer(registry *TokenRegistry, vLog types.Log) *data.TransferEvent {
+	if len(vLog.Topics) != 3 || vLog.Topics[0] != transferSigHash {
+		return nil
+	}
 
-			/*
-				ERC20 Transfer 事件：
-					topics[0] → event signature
-					topics[1] → from
-					topics[2] → to
-					data → amount
-			*/
-			fromAddr := common.HexToAddress(vLog.Topics[1].Hex()).Hex()
-			toAddr := common.HexToAddress(vLog.Topics[2].Hex()).Hex()
-			amount := new(big.Int).SetBytes(vLog.Data)
-			// 🐳 巨鲸过滤：只关注大于 50,000 USDT 的交易 (USDT 有 6 位小数)
-			minAmount := new(big.Int).Mul(big.NewInt(50000), big.NewInt(1000000))
-			if amount.Cmp(minAmount) < 0 {
-				continue
-			}
+	/*
+		ERC20 Transfer 事件：
+			topics[0] → event signature
+			topics[1] → from
+			topics[2] → to
+			data → amount
+	*/
+	fromAddr := common.HexToAddress(vLog.Topics[1].Hex()).Hex()
+	toAddr := common.HexToAddress(vLog.Topics[2].Hex()).Hex()
+	amount := new(big.Int).SetBytes(vLog.Data)
+	// 🐳 巨鲸过滤：每个代币使用各自的阈值
+	if !registry.IsWhale(vLog.Address, amount) {
+		return nil
+	}
 
-			// 封装事件并写入数据库
-			event := &data.TransferEvent{
-				TxHash:       vLog.TxHash.Hex(),
-				LogIndex:     int(vLog.Index),
-				BlockNumber:  int64(vLog.BlockNumber),
-				BlockHash:    vLog.BlockHash.Hex(),
-				FromAddress:  fromAddr,
-				ToAddress:    toAddr,
-				Amount:       amount.String(),
-				TokenAddress: vLog.Address.Hex(),
-			}
+	symbol, formatted := registry.Describe(vLog.Address, amount)
+
+	return &data.TransferEvent{
+		TxHash:       vLog.TxHash.Hex(),
+		LogIndex:     int(vLog.Index),
+		BlockNumber:  int64(vLog.BlockNumber),
+		BlockHash:    vLog.BlockHash.Hex(),
+		FromAddress:  fromAddr,
+		ToAddress:    toAddr,
+		Amount:       amount.String(),
+		TokenAddress: vLog.Address.Hex(),
+		BlockTime:    blockTime(vLog.BlockTimestamp),
+
+		Symbol:          symbol,
+		AmountFormatted: formatted,
+	}
+}
 
-			if insertErr := e.models.TransferEvents.InsertTx(ctx, tx, event); insertErr != nil {
-				e.logger.Error("failed to insert transactional event", "tx_hash", event.TxHash, "error", insertErr)
-				return insertErr
+// fillBlockTimes 为缺少出块时间的事件补全时间戳（只处理解析出巨鲸转账或解码事件的日志）
+// 较新的节点会在 eth_getLogs 结果中直接返回 blockTimestamp；老节点不返回时，批量查询涉及区块的区块头
+func (e *Engine) fillBlockTimes(ctx context.Context, b *batch) error {
+	seen := make(map[int64]bool)
+	var missing []int64
+	for _, l := range b.logs {
+		if (l.Transfer != nil && l.Transfer.BlockTime.IsZero()) || (l.Decoded != nil && l.Decoded.BlockTime.IsZero()) {
+			if n := int64(l.Raw.BlockNumber); !seen[n] {
+				seen[n] = true
+				missing = append(missing, n)
 			}
-			pendingPushEvents = append(pendingPushEvents, event)
 		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
 
-		// [V2升级] 自动重试获取目标区块头
-		targetHeader, err := e.getHeaderByNumber(ctx, toBlock)
-		if err != nil {
-			return fmt.Errorf("failed to fetch target block header: %w", err)
+	headers, err := e.getHeaders(ctx, missing)
+	if err != nil {
+		return fmt.Errorf("failed to fetch block headers for timestamps: %w", err)
+	}
+
+	for _, l := range b.logs {
+		t := blockTime(0)
+		if header, ok := headers[int64(l.Raw.BlockNumber)]; ok {
+			t = blockTime(header.Time)
+		}
+		if l.Transfer != nil && l.Transfer.BlockTime.IsZero() {
+			l.Transfer.BlockTime = t
 		}
+		if l.Decoded != nil && l.Decoded.BlockTime.IsZero() {
+			l.Decoded.BlockTime = t
+		}
+	}
+	return nil
+}
 
-		// 更新区块游标
-		trace := &data.BlockTrace{
-			BlockNumber: toBlock,
-			BlockHash:   targetHeader.Hash().Hex(),
-			ParentHash:  targetHeader.ParentHash.Hex(),
+// blockTime 把区块头中的 Unix 秒转换为 UTC 时间，0 表示未知
+func blockTime(timestamp uint64) time.Time {
+	if timestamp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(timestamp), 0).UTC()
+}
+
+// dispatchLogs 在调用方的事务中把窗口内的日志依次交给各个处理器
+func (e *Engine) dispatchLogs(ctx context.Context, tx *sql.Tx, b *batch) error {
+	for _, l := range b.logs {
+		// 🛑 核心拦截：如果插入一半按了 Ctrl+C，立刻报错退出，触发 tx.Rollback()
+		if ctx.Err() != nil {
+			e.logger.Warn("sync canceled during db insert, aborting current batch")
+			return ctx.Err()
 		}
 
-		if traceErr := e.models.BlockTraces.InsertTx(ctx, tx, trace); traceErr != nil {
-			e.logger.Error("failed to update block trace cursor", "block_number", toBlock, "error", traceErr)
-			return traceErr
+		for _, h := range e.handlers {
+			if err := h.OnLog(ctx, tx, l); err != nil {
+				e.logger.Error("event handler failed", "handler", fmt.Sprintf("%T", h), "tx_hash", l.Raw.TxHash.Hex(), "log_index", l.Raw.Index, "error", err)
+				return err
+			}
 		}
+	}
+	return nil
+}
 
-		// 3.事务提交
-		if err = tx.Commit(); err != nil {
-			return err
+// dispatchCommitted 通知各个处理器窗口已提交
+func (e *Engine) dispatchCommitted(ctx context.Context, committed *CommittedBatch) {
+	for _, h := range e.handlers {
+		h.OnBlockCommitted(ctx, committed)
+	}
+}
+
+// dispatchReorg 通知各个处理器 forkBlock 以上的数据已被回滚
+func (e *Engine) dispatchReorg(ctx context.Context, forkBlock int64) {
+	for _, h := range e.handlers {
+		h.OnReorg(ctx, forkBlock)
+	}
+}
+
+// uniqueAddresses 合并多个地址列表并去重，保持首次出现的顺序
+func uniqueAddresses(lists ...[]common.Address) []common.Address {
+	seen := make(map[common.Address]bool)
+	var out []common.Address
+	for _, list := range lists {
+		for _, addr := range list {
+			if !seen[addr] {
+				seen[addr] = true
+				out = append(out, addr)
+			}
 		}
+	}
+	return out
+}
 
-		if e.events != nil {
-			for _, event := range pendingPushEvents {
-				e.events <- event
+// uniqueHashes 合并多个 topic 列表并去重，保持首次出现的顺序
+func uniqueHashes(lists ...[]common.Hash) []common.Hash {
+	seen := make(map[common.Hash]bool)
+	var out []common.Hash
+	for _, list := range lists {
+		for _, h := range list {
+			if !seen[h] {
+				seen[h] = true
+				out = append(out, h)
 			}
 		}
 	}
-	return nil
+	return out
+}
+
+// loadTokenRegistry 从数据库读取监控代币并构建注册表
+// 只用 ABI 文件监控指定合约时代币列表可以为空，两者都为空才报错
+func (e *Engine) loadTokenRegistry() (*TokenRegistry, error) {
+	tokens, err := e.models.Tokens.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load watched tokens: %w", err)
+	}
+
+	registry, err := NewTokenRegistry(tokens)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.watchedAddresses(registry)) == 0 {
+		return nil, ErrNoWatchedTokens
+	}
+	return registry, nil
+}
+
+// watchedAddresses 日志过滤条件中的合约地址：监控代币加上 ABI 文件声明的合约
+func (e *Engine) watchedAddresses(registry *TokenRegistry) []common.Address {
+	return uniqueAddresses(registry.Addresses(), e.decoders.Addresses())
 }
 
 // =========================================================================
 // RPC 辅助方法 (Let's Go Further 风格封装：隔离复杂性，内置超时与重试)
 // =========================================================================
 
+// hedgedSlot 传给 fetchBatch/fetchLogs 时表示走对冲请求，而不是固定优先某个节点
+const hedgedSlot = -1
+
+// archiveDepth 全节点默认只保留最近 128 个区块的状态，更早的区块交给声明了 Archive 能力的节点
+const archiveDepth = 128
+
+// historical 查询距离链头超过 archiveDepth 的区块时，在 ctx 上要求归档节点
+// 没有节点声明 Archive 能力时该要求不生效
+func (e *Engine) historical(ctx context.Context, blockNumber int64) context.Context {
+	head := int64(e.nodeManager.BestHead())
+	if head == 0 || head-blockNumber <= archiveDepth {
+		return ctx
+	}
+	return rpc.WithRequirements(ctx, rpc.Requirements{Archive: true})
+}
+
+// getLatestHeight 获取链头高度；落后于其它节点的节点会被拒绝并标记为不健康，重试时换节点
+// 链头决定巨鲸告警的时效，启用对冲时慢节点会被第二个节点顶替
 func (e *Engine) getLatestHeight(ctx context.Context) (int64, error) {
-	var height int64
-	err := e.nodeManager.ExecuteWithRetry(func(client *ethclient.Client) error {
+	return rpc.Hedged(ctx, e.nodeManager, "eth_getBlockByNumber:latest", func(ctx context.Context, node *rpc.Node) (int64, error) {
 		timeoutCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 		defer cancel()
-		header, err := client.HeaderByNumber(timeoutCtx, nil)
+		header, err := node.Client.HeaderByNumber(timeoutCtx, nil)
 		if err != nil {
-			return err
+			return 0, err
 		}
-		height = header.Number.Int64()
-		return nil
+		if err := e.nodeManager.RecordHead(node, header.Number.Uint64()); err != nil {
+			return 0, err
+		}
+		return header.Number.Int64(), nil
 	})
-	return height, err
+}
+
+// getConfirmedHeight 根据配置计算本轮允许同步到的最高区块
+func (e *Engine) getConfirmedHeight(ctx context.Context, chainHeight int64) (int64, error) {
+	var tag ethrpc.BlockNumber
+	switch e.config.FinalityTag {
+	case FinalitySafe:
+		tag = ethrpc.SafeBlockNumber
+	case FinalityFinalized:
+		tag = ethrpc.FinalizedBlockNumber
+	default:
+		return chainHeight - e.config.Confirmations, nil
+	}
+
+	header, err := e.getHeaderByNumber(ctx, tag.Int64())
+	if err != nil {
+		return 0, err
+	}
+	return header.Number.Int64(), nil
 }
 
 func (e *Engine) getHeaderByNumber(ctx context.Context, blockNumber int64) (*types.Header, error) {
+	if blockNumber >= 0 {
+		ctx = e.historical(ctx, blockNumber)
+	}
+
 	var targetHeader *types.Header
-	err := e.nodeManager.ExecuteWithRetry(func(client *ethclient.Client) error {
+	err := e.nodeManager.ExecuteContext(ctx, func(ctx context.Context, node *rpc.Node) error {
 		timeoutCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 		defer cancel()
-		header, err := client.HeaderByNumber(timeoutCtx, big.NewInt(blockNumber))
+		header, err := node.Client.HeaderByNumber(timeoutCtx, big.NewInt(blockNumber))
 		if err != nil {
 			return err
 		}
@@ -289,13 +692,59 @@ func (e *Engine) getHeaderByNumber(ctx context.Context, blockNumber int64) (*typ
 	return targetHeader, err
 }
 
-func (e *Engine) fetchLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+// getHeaders 通过 JSON-RPC 批量请求获取一组区块头，返回按区块号索引的结果
+// 个别区块在所选节点上还不存在（返回 null）时，单独向其它节点补查
+func (e *Engine) getHeaders(ctx context.Context, numbers []int64) (map[int64]*types.Header, error) {
+	if len(numbers) > 0 {
+		ctx = e.historical(ctx, slices.Min(numbers))
+	}
+
+	results := make([]*types.Header, len(numbers))
+	elems := make([]ethrpc.BatchElem, len(numbers))
+	for i, n := range numbers {
+		elems[i] = ethrpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []any{hexutil.EncodeBig(big.NewInt(n)), false},
+			Result: &results[i],
+		}
+	}
+
+	if err := e.nodeManager.BatchCall(ctx, elems); err != nil {
+		return nil, err
+	}
+
+	headers := make(map[int64]*types.Header, len(numbers))
+	for i, n := range numbers {
+		if elems[i].Error != nil {
+			return nil, fmt.Errorf("block %d: %w", n, elems[i].Error)
+		}
+		header := results[i]
+		if header == nil {
+			var err error
+			if header, err = e.getHeaderByNumber(ctx, n); err != nil {
+				return nil, fmt.Errorf("block %d: %w", n, err)
+			}
+		}
+		headers[n] = header
+	}
+	return headers, nil
+}
+
+// fetchLogs 抓取 query 区间内的全部日志
+// 区间超过节点已知上限时按上限分段；服务商返回“结果过多”时自动二分，并记住该节点能承受的跨度
+// slot 为 hedgedSlot 时发起对冲请求
+func (e *Engine) fetchLogs(ctx context.Context, slot int, query ethereum.FilterQuery) ([]types.Log, error) {
+	ctx = rpc.WithRequirements(ctx, rpc.Requirements{LogRange: query.ToBlock.Int64() - query.FromBlock.Int64() + 1})
+
+	if slot == hedgedSlot {
+		return rpc.Hedged(ctx, e.nodeManager, "eth_getLogs", func(ctx context.Context, node *rpc.Node) ([]types.Log, error) {
+			return e.filterLogsAdaptive(ctx, node, query)
+		})
+	}
+
 	var logs []types.Log
-	err := e.nodeManager.ExecuteWithRetry(func(client *ethclient.Client) error {
-		// 查询日志通常比较耗时，这里给了 10 秒超时
-		timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-		defer cancel()
-		fetchedLogs, err := client.FilterLogs(timeoutCtx, query)
+	err := e.nodeManager.ExecuteContextAt(ctx, slot, func(ctx context.Context, node *rpc.Node) error {
+		fetchedLogs, err := e.filterLogsAdaptive(ctx, node, query)
 		if err != nil {
 			return err
 		}
@@ -304,3 +753,39 @@ func (e *Engine) fetchLogs(ctx context.Context, query ethereum.FilterQuery) ([]t
 	})
 	return logs, err
 }
+
+func (e *Engine) filterLogsAdaptive(ctx context.Context, node *rpc.Node, query ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+
+	from, to := query.FromBlock.Int64(), query.ToBlock.Int64()
+	for start := from; start <= to; {
+		span := min(e.logRange.ceiling(node.Config.Name), to-start+1)
+		// 节点声明了跨度上限时直接按上限分段，不必先被拒绝一次
+		if limit := node.Config.Capabilities.MaxLogRange; limit > 0 {
+			span = min(span, limit)
+		}
+
+		chunkQuery := query
+		chunkQuery.FromBlock = big.NewInt(start)
+		chunkQuery.ToBlock = big.NewInt(start + span - 1)
+
+		// 查询日志通常比较耗时，这里给了 10 秒超时
+		timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		chunk, err := node.Client.FilterLogs(timeoutCtx, chunkQuery)
+		cancel()
+
+		if err != nil {
+			if !rpc.IsLogRangeError(err) || span == 1 {
+				return nil, err
+			}
+			ceiling := e.logRange.shrink(node.Config.Name, span)
+			e.logger.Warn("log range rejected by node, splitting request", "node", node.Config.Name, "span", span, "new_ceiling", ceiling)
+			continue
+		}
+
+		e.logRange.observe(node.Config.Name, span, len(chunk))
+		logs = append(logs, chunk...)
+		start += span
+	}
+	return logs, nil
+}
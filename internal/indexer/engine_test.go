@@ -0,0 +1,155 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/chaintest"
+	"github.com/zy99978455-otw/flash-monitor/internal/data"
+	"github.com/zy99978455-otw/flash-monitor/internal/decoder"
+	"github.com/zy99978455-otw/flash-monitor/internal/rpc"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// replayManager 创建一个从 testdata/<fixture>.json 回放响应的节点管理器，不访问网络
+func replayManager(t *testing.T, fixture string) (*rpc.Manager, *rpc.Replayer) {
+	t.Helper()
+
+	replayer, err := rpc.LoadReplayer(filepath.Join("testdata", fixture+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := rpc.NewManager(
+		[]rpc.NodeConfig{{Name: "fixture", URL: "http://127.0.0.1:1"}},
+		rpc.Options{Transport: replayer},
+		testLogger(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Stop)
+	return m, replayer
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// testdata/whale_transfers.json 录制了区块 100-105 的日志：
+// 100 与 103 为 USDT 巨鲸转账（103 的日志不带 blockTimestamp），101 为低于阈值的 USDT 转账，
+// 102 为未监控代币的大额转账，104 为 USDT 的 Approval 事件
+func TestFetchBatchWhaleFilter(t *testing.T) {
+	m, replayer := replayManager(t, "whale_transfers")
+	e := NewEngine(m, data.Models{}, testLogger(), Config{})
+
+	registry, err := NewTokenRegistry([]*data.Token{{
+		Address:        "0xdAC17F958D2ee523a2206206994597C13D831ec7",
+		Symbol:         "USDT",
+		Decimals:       6,
+		WhaleThreshold: "1000000",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fetched, err := e.fetchBatch(context.Background(), 0, registry, 100, 105)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if transfers, decoded := fetched.counts(); transfers != 2 || decoded != 0 {
+		t.Fatalf("counts() = (%d, %d), want (2, 0)", transfers, decoded)
+	}
+
+	want := []struct {
+		block  int64
+		amount string
+	}{
+		{100, "2000000000000"},
+		{103, "5000000000000"},
+	}
+	var got []*data.TransferEvent
+	for _, l := range fetched.logs {
+		if l.Transfer != nil {
+			got = append(got, l.Transfer)
+		}
+	}
+	for i, w := range want {
+		ev := got[i]
+		if ev.BlockNumber != w.block || ev.Amount != w.amount {
+			t.Errorf("transfer %d = block %d amount %s, want block %d amount %s", i, ev.BlockNumber, ev.Amount, w.block, w.amount)
+		}
+		if ev.Symbol != "USDT" {
+			t.Errorf("transfer %d symbol = %q, want USDT", i, ev.Symbol)
+		}
+		// 两条日志的时间分别来自 blockTimestamp 字段与补查的区块头
+		if wantTime := time.Unix(1_700_000_000+12*w.block, 0).UTC(); !ev.BlockTime.Equal(wantTime) {
+			t.Errorf("transfer %d block time = %v, want %v", i, ev.BlockTime, wantTime)
+		}
+	}
+
+	if pending := replayer.Pending(); len(pending) != 0 {
+		t.Errorf("recorded rpc calls never made: %v", pending)
+	}
+}
+
+// 只通过 ABI 文件监控指定合约：代币列表为空，日志过滤条件与解码都来自 ABI 文件声明的地址
+func TestFetchBatchDecoderOnly(t *testing.T) {
+	pair := common.HexToAddress("0x0d4a11d5eeaac28ec3f61d100daf4d40471f1852")
+
+	chain := chaintest.NewChain()
+	chain.MineEmpty(5)
+	chain.Mine(chaintest.Transfer{Token: pair, From: common.HexToAddress("0x01"), To: common.HexToAddress("0x02"), Amount: big.NewInt(7)})
+	chain.Mine(usdt(5000)) // 未监控的 USDT 不在过滤条件中
+
+	decoders := decoder.NewRegistry()
+	err := decoders.Register(decoder.Spec{
+		Name:      "pair",
+		Addresses: []string{strings.ToLower(pair.Hex())},
+		ABI:       json.RawMessage(`[{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256"}]}]`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := rpc.NewManager([]rpc.NodeConfig{{Name: "node0", URL: chaintest.NewNode(t, chain).URL(), Timeout: time.Second}}, rpc.Options{}, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Stop)
+	e := NewEngine(m, data.Models{}, testLogger(), Config{Decoders: decoders})
+
+	registry, err := NewTokenRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addrs := e.watchedAddresses(registry); len(addrs) != 1 || addrs[0] != pair {
+		t.Fatalf("watched addresses = %v, want only the abi contract", addrs)
+	}
+
+	fetched, err := e.fetchBatch(context.Background(), 0, registry, 1, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transfers, decoded := fetched.counts(); transfers != 0 || decoded != 1 {
+		t.Fatalf("counts() = (%d, %d), want (0, 1)", transfers, decoded)
+	}
+	ev := fetched.logs[0].Decoded
+	if ev.ContractAddress != pair.Hex() || ev.BlockNumber != 6 || ev.Source != "pair" {
+		t.Errorf("decoded event = %+v", ev)
+	}
+
+	// 代币列表与 ABI 地址都为空时拒绝抓取全网日志
+	empty := NewEngine(m, data.Models{}, testLogger(), Config{})
+	if addrs := empty.watchedAddresses(registry); len(addrs) != 0 {
+		t.Errorf("watched addresses without tokens or abi contracts = %v, want none", addrs)
+	}
+}
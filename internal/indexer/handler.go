@@ -0,0 +1,110 @@
+package indexer
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/data"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Handler 引擎的事件处理器（回调）
+// 引擎负责抓取、解析与游标维护，处理器只关心如何消费结果；新增下游（消息队列、Webhook……）实现该接口即可，无需修改引擎
+type Handler interface {
+	// OnLog 在窗口的写库事务内对每条日志调用一次，按链上顺序；返回错误会回滚整个窗口并在下一轮重试
+	OnLog(ctx context.Context, tx *sql.Tx, log *Log) error
+	// OnBlockCommitted 在窗口事务提交成功之后调用
+	OnBlockCommitted(ctx context.Context, batch *CommittedBatch)
+	// OnReorg 在引擎回滚 forkBlock 以上的数据之后调用，forkBlock 本身仍然有效
+	OnReorg(ctx context.Context, forkBlock int64)
+}
+
+// Log 一条已解析的链上日志
+type Log struct {
+	Raw      types.Log
+	Transfer *data.TransferEvent // 监控代币达到巨鲸阈值的 Transfer，否则为 nil
+	Decoded  *data.DecodedEvent  // ABI 文件注册的事件，否则为 nil
+}
+
+// CommittedBatch 一个已提交的区块窗口
+type CommittedBatch struct {
+	FromBlock int64
+	ToBlock   int64
+	Logs      []*Log
+	// Backfill 为 true 表示历史回填批次，实时推送类处理器通常应忽略
+	Backfill bool
+}
+
+// Transfers 返回窗口内的全部巨鲸转账
+func (b *CommittedBatch) Transfers() []*data.TransferEvent {
+	var events []*data.TransferEvent
+	for _, l := range b.Logs {
+		if l.Transfer != nil {
+			events = append(events, l.Transfer)
+		}
+	}
+	return events
+}
+
+// =========================================================================
+// 内置处理器
+// =========================================================================
+
+// PostgresWriter 把巨鲸转账写入 transfer_events，把解码事件写入 decoded_events
+// 重组时的删除由引擎在回滚游标的同一事务中完成，因此 OnReorg 无需处理
+type PostgresWriter struct {
+	models data.Models
+}
+
+// NewPostgresWriter 创建数据库写入处理器
+func NewPostgresWriter(models data.Models) *PostgresWriter {
+	return &PostgresWriter{models: models}
+}
+
+func (w *PostgresWriter) OnLog(ctx context.Context, tx *sql.Tx, log *Log) error {
+	if log.Transfer != nil {
+		if err := w.models.TransferEvents.InsertTx(ctx, tx, log.Transfer); err != nil {
+			return err
+		}
+	}
+	if log.Decoded != nil {
+		if err := w.models.DecodedEvents.InsertTx(ctx, tx, log.Decoded); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *PostgresWriter) OnBlockCommitted(ctx context.Context, batch *CommittedBatch) {}
+
+func (w *PostgresWriter) OnReorg(ctx context.Context, forkBlock int64) {}
+
+// Broadcaster 把实时同步提交的巨鲸转账推送给 SSE 广播通道，历史回填批次不推送
+type Broadcaster struct {
+	events chan<- *data.TransferEvent
+}
+
+// NewBroadcaster 创建推送处理器
+func NewBroadcaster(events chan<- *data.TransferEvent) *Broadcaster {
+	return &Broadcaster{events: events}
+}
+
+func (b *Broadcaster) OnLog(ctx context.Context, tx *sql.Tx, log *Log) error {
+	return nil
+}
+
+func (b *Broadcaster) OnBlockCommitted(ctx context.Context, batch *CommittedBatch) {
+	if batch.Backfill {
+		return
+	}
+	for _, event := range batch.Transfers() {
+		select {
+		case b.events <- event:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (b *Broadcaster) OnReorg(ctx context.Context, forkBlock int64) {}
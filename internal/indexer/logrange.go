@@ -0,0 +1,78 @@
+package indexer
+
+import (
+	"sync"
+)
+
+const (
+	// 响应日志数低于该值时扩大窗口，高于 logRangeHighWater 时主动收缩，
+	// 两者都远低于 Infura 等服务商 10,000 条的单次上限
+	logRangeLowWater  = 2000
+	logRangeHighWater = 5000
+)
+
+// logRangeSizer 自适应 eth_getLogs 的区块窗口大小
+// 响应小就倍增窗口；服务商返回“结果过多”时对半拆分，并记住该节点能承受的最大跨度
+type logRangeSizer struct {
+	mu       sync.Mutex
+	size     int64
+	max      int64
+	ceilings map[string]int64 // 节点名 -> 已探测到的最大可用跨度
+}
+
+func newLogRangeSizer(initial, maxSpan int64) *logRangeSizer {
+	maxSpan = max(maxSpan, 1)
+	return &logRangeSizer{
+		size:     min(max(initial, 1), maxSpan),
+		max:      maxSpan,
+		ceilings: make(map[string]int64),
+	}
+}
+
+// window 返回下一批次建议的区块跨度
+func (s *logRangeSizer) window() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// ceiling 返回指定节点单次请求允许的最大跨度
+func (s *logRangeSizer) ceiling(node string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.ceilings[node]; ok {
+		return c
+	}
+	return s.max
+}
+
+// observe 根据一次成功请求的结果数量调整窗口
+func (s *logRangeSizer) observe(node string, span int64, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case count > logRangeHighWater && s.size > 1:
+		s.size = max(s.size/2, 1)
+	case count < logRangeLowWater && span >= s.size:
+		// 只有跑满当前窗口的请求才能证明窗口还有余量
+		limit := s.max
+		if c, ok := s.ceilings[node]; ok {
+			limit = c
+		}
+		s.size = min(s.size*2, limit)
+	}
+}
+
+// shrink 在节点拒绝 span 跨度的请求后调用，记录该节点的上限并收缩全局窗口
+func (s *logRangeSizer) shrink(node string, span int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ceiling := max(span/2, 1)
+	if c, ok := s.ceilings[node]; !ok || ceiling < c {
+		s.ceilings[node] = ceiling
+	}
+	s.size = min(s.size, ceiling)
+	return ceiling
+}
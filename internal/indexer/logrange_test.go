@@ -0,0 +1,153 @@
+package indexer
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/chaintest"
+	"github.com/zy99978455-otw/flash-monitor/internal/data"
+	"github.com/zy99978455-otw/flash-monitor/internal/rpc"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestLogRangeSizerObserve(t *testing.T) {
+	s := newLogRangeSizer(6, 100)
+
+	// 跑满窗口且结果少时倍增，直到全局上限
+	for _, want := range []int64{12, 24, 48, 96, 100, 100} {
+		s.observe("a", s.window(), 10)
+		if got := s.window(); got != want {
+			t.Fatalf("window after a small full-span response = %d, want %d", got, want)
+		}
+	}
+
+	// 未跑满窗口的请求（例如追到链头的尾批次）不能证明窗口还有余量
+	s = newLogRangeSizer(6, 100)
+	s.observe("a", 3, 10)
+	if got := s.window(); got != 6 {
+		t.Errorf("window after a partial-span response = %d, want 6", got)
+	}
+
+	// 结果数介于高低水位之间时保持不变
+	s.observe("a", 6, logRangeLowWater)
+	if got := s.window(); got != 6 {
+		t.Errorf("window after a medium response = %d, want 6", got)
+	}
+
+	// 结果过多时对半收缩，最小为 1
+	for _, want := range []int64{3, 1, 1} {
+		s.observe("a", s.window(), logRangeHighWater+1)
+		if got := s.window(); got != want {
+			t.Fatalf("window after a large response = %d, want %d", got, want)
+		}
+	}
+}
+
+func TestNewLogRangeSizerBounds(t *testing.T) {
+	tests := []struct {
+		initial, maxSpan int64
+		window           int64
+	}{
+		{6, 2000, 6},
+		{6, 4, 4},
+		{0, 2000, 1},
+		{6, 0, 1},
+	}
+	for _, tt := range tests {
+		s := newLogRangeSizer(tt.initial, tt.maxSpan)
+		if got := s.window(); got != tt.window {
+			t.Errorf("newLogRangeSizer(%d, %d).window() = %d, want %d", tt.initial, tt.maxSpan, got, tt.window)
+		}
+	}
+}
+
+func TestLogRangeSizerShrink(t *testing.T) {
+	s := newLogRangeSizer(64, 1000)
+
+	if got := s.ceiling("a"); got != 1000 {
+		t.Errorf("ceiling before any rejection = %d, want the global max", got)
+	}
+
+	// 被拒绝后记录节点上限，并把全局窗口收缩到不超过该上限
+	if c := s.shrink("a", 64); c != 32 {
+		t.Errorf("shrink(64) = %d, want 32", c)
+	}
+	if got := s.ceiling("a"); got != 32 {
+		t.Errorf("ceiling(a) = %d, want 32", got)
+	}
+	if got := s.window(); got != 32 {
+		t.Errorf("window after shrink = %d, want 32", got)
+	}
+
+	// 上限只降不升：较大跨度的拒绝不会放宽已探测到的上限
+	if c := s.shrink("a", 200); c != 100 {
+		t.Errorf("shrink(200) = %d, want 100", c)
+	}
+	if got := s.ceiling("a"); got != 32 {
+		t.Errorf("ceiling(a) after a wider rejection = %d, want 32", got)
+	}
+
+	// 其它节点不受影响，倍增时按各自的上限截断
+	if got := s.ceiling("b"); got != 1000 {
+		t.Errorf("ceiling(b) = %d, want the global max", got)
+	}
+	s.observe("a", 32, 10)
+	if got := s.window(); got != 32 {
+		t.Errorf("window after growing on node a = %d, want its ceiling 32", got)
+	}
+	s.observe("b", 32, 10)
+	if got := s.window(); got != 64 {
+		t.Errorf("window after growing on node b = %d, want 64", got)
+	}
+
+	if c := s.shrink("a", 1); c != 1 {
+		t.Errorf("shrink(1) = %d, want 1", c)
+	}
+}
+
+func TestFilterLogsAdaptiveSplitsRejectedRange(t *testing.T) {
+	chain := chaintest.NewChain()
+	for range 40 {
+		chain.Mine(usdt(1))
+	}
+	node := chaintest.NewNode(t, chain)
+	node.SetMaxLogRange(8)
+
+	m, err := rpc.NewManager([]rpc.NodeConfig{{Name: "limited", URL: node.URL()}}, rpc.Options{}, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Stop)
+	e := NewEngine(m, data.Models{}, testLogger(), Config{MaxLogRange: 32})
+
+	logs, err := e.fetchLogs(context.Background(), 0, ethereum.FilterQuery{
+		FromBlock: big.NewInt(1),
+		ToBlock:   big.NewInt(40),
+		Addresses: []common.Address{chaintest.USDT},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 40 {
+		t.Fatalf("fetched %d logs, want 40", len(logs))
+	}
+	for i, l := range logs {
+		if l.BlockNumber != uint64(i+1) {
+			t.Fatalf("log %d is from block %d, want %d: chunks out of order or duplicated", i, l.BlockNumber, i+1)
+		}
+	}
+
+	// 32 与 16 个块的请求被拒绝后，节点上限定为 8，剩余区间按 8 个块分段
+	if got := e.logRange.ceiling("limited"); got != 8 {
+		t.Errorf("ceiling = %d, want 8", got)
+	}
+	if calls := node.Calls("eth_getLogs"); calls != 7 {
+		t.Errorf("eth_getLogs calls = %d, want 7 (2 rejected + 5 chunks)", calls)
+	}
+	if got := e.logRange.window(); got != 8 {
+		t.Errorf("window = %d, want it grown to the node ceiling 8", got)
+	}
+}
@@ -0,0 +1,181 @@
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/rpc"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ERC20 元数据方法的函数选择器
+var (
+	symbolSelector   = crypto.Keccak256([]byte("symbol()"))[:4]
+	decimalsSelector = crypto.Keccak256([]byte("decimals()"))[:4]
+	nameSelector     = crypto.Keccak256([]byte("name()"))[:4]
+)
+
+// TokenMetadata 通过 eth_call 读取到的代币元数据
+type TokenMetadata struct {
+	Symbol   string
+	Name     string
+	Decimals int
+}
+
+// ResolveTokenMetadata 为尚未与链上核对过的监控代币解析 symbol、decimals 与 name，并缓存到 tokens 表
+// 单个代币解析失败只记录日志，不影响其它代币和引擎启动
+func (e *Engine) ResolveTokenMetadata(ctx context.Context) error {
+	tokens, err := e.models.Tokens.GetAll()
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		if token.ResolvedAt != nil {
+			continue
+		}
+
+		meta, err := e.resolveToken(ctx, common.HexToAddress(token.Address))
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			e.logger.Warn("failed to resolve token metadata", "address", token.Address, "error", err)
+			continue
+		}
+
+		// 链上返回空值时保留配置文件中的值；过长的值按列宽截断
+		if meta.Symbol != "" {
+			token.Symbol = truncateUTF8(meta.Symbol, 32)
+		}
+		if meta.Name != "" {
+			token.Name = truncateUTF8(meta.Name, 128)
+		}
+		if meta.Decimals >= 0 {
+			if meta.Decimals != token.Decimals {
+				e.logger.Warn("configured decimals differ from on-chain value, using on-chain value",
+					"address", token.Address, "configured", token.Decimals, "on_chain", meta.Decimals)
+			}
+			token.Decimals = meta.Decimals
+		}
+
+		if err := e.models.Tokens.UpdateMetadata(token); err != nil {
+			return err
+		}
+		e.logger.Info("token metadata resolved", "address", token.Address, "symbol", token.Symbol, "name", token.Name, "decimals", token.Decimals)
+	}
+	return nil
+}
+
+// resolveToken 依次调用 symbol()、decimals()、name()
+// 未实现某个方法的合约（调用被 revert）对应字段留空，Decimals 为 -1 表示未知
+func (e *Engine) resolveToken(ctx context.Context, token common.Address) (*TokenMetadata, error) {
+	meta := &TokenMetadata{Decimals: -1}
+
+	out, err := e.callContract(ctx, token, symbolSelector)
+	if err != nil {
+		return nil, err
+	}
+	meta.Symbol = decodeStringResult(out)
+
+	out, err = e.callContract(ctx, token, nameSelector)
+	if err != nil {
+		return nil, err
+	}
+	meta.Name = decodeStringResult(out)
+
+	out, err = e.callContract(ctx, token, decimalsSelector)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) >= 32 {
+		decimals := new(big.Int).SetBytes(out[:32])
+		if decimals.IsInt64() && decimals.Int64() <= 77 {
+			meta.Decimals = int(decimals.Int64())
+		}
+	}
+
+	if meta.Symbol == "" && meta.Decimals < 0 {
+		return nil, errors.New("contract does not look like an ERC20 token")
+	}
+	return meta, nil
+}
+
+// callContract 通过节点管理器执行 eth_call
+// 合约执行错误（revert 等 JSON-RPC 错误）是确定性的，节点管理器不会重试也不计入节点故障，这里按空结果处理
+func (e *Engine) callContract(ctx context.Context, to common.Address, input []byte) ([]byte, error) {
+	var out []byte
+	err := e.nodeManager.ExecuteContext(ctx, func(ctx context.Context, node *rpc.Node) error {
+		timeoutCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		result, err := node.Client.CallContract(timeoutCtx, ethereum.CallMsg{To: &to, Data: input}, nil)
+		if err != nil {
+			return err
+		}
+		out = result
+		return nil
+	})
+	if rpc.Classify(err) == rpc.ClassExecution {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("eth_call %s: %w", to.Hex(), err)
+	}
+	return out, nil
+}
+
+// decodeStringResult 解析 symbol()/name() 的返回值
+// 标准 ERC20 返回 ABI 编码的 string；MKR 等早期代币返回 bytes32，需要去掉末尾的 0 字节
+func decodeStringResult(out []byte) string {
+	if len(out) >= 64 {
+		// 先与剩余长度比较再做加法，超大的 offset/length 不会溢出后绕过检查
+		size := uint64(len(out))
+		offset := new(big.Int).SetBytes(out[:32])
+		if offset.IsUint64() && offset.Uint64() <= size-32 {
+			start := offset.Uint64()
+			length := new(big.Int).SetBytes(out[start : start+32])
+			if length.IsUint64() && length.Uint64() <= size-start-32 {
+				return sanitizeString(out[start+32 : start+32+length.Uint64()])
+			}
+		}
+	}
+
+	if len(out) == 32 {
+		return sanitizeString(bytes.TrimRight(out, "\x00"))
+	}
+	return ""
+}
+
+// sanitizeString 丢弃非法 UTF-8 与控制字符，避免恶意代币把脏数据写进数据库；不做 HTML 转义，前端渲染时须自行转义
+func sanitizeString(b []byte) string {
+	if !utf8.Valid(b) {
+		return ""
+	}
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, string(b)))
+}
+
+// truncateUTF8 按字节数截断字符串，且不会截断在多字节字符中间
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,68 @@
+package indexer
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// word 把整数编码为一个 32 字节的 ABI 字
+func word(n *big.Int) []byte {
+	return common.LeftPadBytes(n.Bytes(), 32)
+}
+
+// abiString 构造标准 ABI 编码的 string 返回值
+func abiString(s string) []byte {
+	out := append(word(big.NewInt(32)), word(big.NewInt(int64(len(s))))...)
+	return append(out, common.RightPadBytes([]byte(s), (len(s)+31)/32*32)...)
+}
+
+func TestDecodeStringResult(t *testing.T) {
+	maxUint64 := new(big.Int).SetUint64(math.MaxUint64)
+
+	tests := []struct {
+		name string
+		out  []byte
+		want string
+	}{
+		{"abi string", abiString("USDT"), "USDT"},
+		{"abi string spanning words", abiString("Wrapped Ether Token On Mainnet Chain"), "Wrapped Ether Token On Mainnet Chain"},
+		{"empty abi string", abiString(""), ""},
+		{"bytes32", common.RightPadBytes([]byte("MKR"), 32), "MKR"},
+		{"bytes32 with control characters", common.RightPadBytes([]byte("MK\nR\x7f"), 32), "MKR"},
+		{"invalid utf-8", common.RightPadBytes([]byte{0xff, 0xfe}, 32), ""},
+		{"empty result", nil, ""},
+		{"short result", []byte("USDT"), ""},
+		{"offset beyond result", append(word(big.NewInt(64)), word(big.NewInt(4))...), ""},
+		{"offset near 2^64", append(word(new(big.Int).Sub(maxUint64, big.NewInt(16))), word(big.NewInt(4))...), ""},
+		{"offset wider than 64 bits", append(word(new(big.Int).Lsh(big.NewInt(1), 200)), word(big.NewInt(4))...), ""},
+		{"length beyond result", append(word(big.NewInt(32)), word(big.NewInt(33))...), ""},
+		{"length near 2^64", append(word(big.NewInt(32)), word(new(big.Int).Sub(maxUint64, big.NewInt(16)))...), ""},
+		{"length wider than 64 bits", append(word(big.NewInt(32)), word(new(big.Int).Lsh(big.NewInt(1), 200))...), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeStringResult(tt.out); got != tt.want {
+				t.Errorf("decodeStringResult(%x) = %q, want %q", tt.out, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	// "币" 占 3 个字节，截断点落在字符中间时退回到字符边界
+	s := "ab" + "币"
+	if got := truncateUTF8(s, 4); got != "ab" {
+		t.Errorf("truncateUTF8(%q, 4) = %q, want %q", s, got, "ab")
+	}
+	if got := truncateUTF8(s, 5); got != s {
+		t.Errorf("truncateUTF8(%q, 5) = %q, want %q", s, got, s)
+	}
+	if got := truncateUTF8(string(bytes.Repeat([]byte("a"), 40)), 32); len(got) != 32 {
+		t.Errorf("truncateUTF8 to 32 bytes returned %d bytes", len(got))
+	}
+}
@@ -0,0 +1,81 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/rpc"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+)
+
+// getCanonicalHeader 获取重组检测使用的单个区块头，启用 Quorum 时需多个节点确认
+func (e *Engine) getCanonicalHeader(ctx context.Context, blockNumber int64) (*types.Header, error) {
+	if e.config.Quorum <= 1 {
+		return e.getHeaderByNumber(ctx, blockNumber)
+	}
+	headers, err := e.getCanonicalHeaders(ctx, []int64{blockNumber})
+	if err != nil {
+		return nil, err
+	}
+	return headers[blockNumber], nil
+}
+
+// getCanonicalHeaders 获取重组检测与游标写入使用的区块头
+// 启用 Quorum 时向全部健康节点批量请求同一组区块头，至少 Quorum 个节点的哈希完全一致才采信，
+// 避免单个分叉或返回叔块链的服务商触发错误回滚；结果与多数派不一致的节点被隔离
+func (e *Engine) getCanonicalHeaders(ctx context.Context, numbers []int64) (map[int64]*types.Header, error) {
+	if e.config.Quorum <= 1 || len(numbers) == 0 {
+		return e.getHeaders(ctx, numbers)
+	}
+
+	fetch := func(ctx context.Context, node *rpc.Node) ([]*types.Header, error) {
+		results := make([]*types.Header, len(numbers))
+		elems := make([]ethrpc.BatchElem, len(numbers))
+		for i, n := range numbers {
+			elems[i] = ethrpc.BatchElem{
+				Method: "eth_getBlockByNumber",
+				Args:   []any{hexutil.EncodeBig(big.NewInt(n)), false},
+				Result: &results[i],
+			}
+		}
+		if err := node.BatchCall(ctx, elems); err != nil {
+			return nil, err
+		}
+		for i, n := range numbers {
+			if elems[i].Error != nil {
+				return nil, fmt.Errorf("block %d: %w", n, elems[i].Error)
+			}
+			// 节点还没有这个区块时不参与投票
+			if results[i] == nil {
+				return nil, fmt.Errorf("block %d: %w", n, ethereum.NotFound)
+			}
+		}
+		return results, nil
+	}
+
+	hashes := func(headers []*types.Header) string {
+		var sb strings.Builder
+		for _, header := range headers {
+			sb.WriteString(header.Hash().Hex())
+		}
+		return sb.String()
+	}
+
+	// 每个节点的一次投票包含 len(numbers) 个调用，按调用数扣减各节点额度
+	results, err := rpc.Quorum(rpc.WithCalls(ctx, len(numbers)), e.nodeManager, e.config.Quorum, fetch, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("blocks %d-%d: %w", numbers[0], numbers[len(numbers)-1], err)
+	}
+
+	headers := make(map[int64]*types.Header, len(numbers))
+	for i, n := range numbers {
+		headers[n] = results[i]
+	}
+	return headers, nil
+}
@@ -0,0 +1,96 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/data"
+)
+
+// ErrReorgTooDeep 重组深度超过 MaxReorgDepth，引擎拒绝自动回滚
+var ErrReorgTooDeep = errors.New("chain reorg exceeds maximum depth")
+
+// handleReorg 检测链重组并回滚到共同祖先
+//  1. 快速路径：最新游标的哈希与规范链一致，说明没有分叉
+//  2. 否则用一次批量请求取回 MaxReorgDepth 范围内全部历史游标对应的规范区块头，
+//     自上而下找到第一个与规范链一致的区块（共同祖先）
+//  3. 在单个事务中删除祖先之上的全部事件与游标，窗口中间从未被单独校验过的区块也一并清理
+func (e *Engine) handleReorg(ctx context.Context) error {
+	latestTrace, err := e.models.BlockTraces.GetLatest()
+	if err != nil {
+		return err
+	}
+	if latestTrace == nil {
+		return nil //冷启动
+	}
+
+	// [V2升级] 自动重试获取区块头
+	canonical, err := e.getCanonicalHeader(ctx, latestTrace.BlockNumber)
+	if err != nil {
+		return fmt.Errorf("failed to fetch header for reorg check: %w", err)
+	}
+	if canonical.Hash().Hex() == latestTrace.BlockHash {
+		return nil //祖先一致，未分叉
+	}
+
+	e.logger.Warn("Chain reorg detected! Searching for common ancestor...",
+		"blockNumber", latestTrace.BlockNumber,
+		"db_Hash", latestTrace.BlockHash,
+		"canonical_rpc_hash", canonical.Hash().Hex(),
+	)
+
+	traces, err := e.models.BlockTraces.GetBelow(latestTrace.BlockNumber, int(e.config.MaxReorgDepth)+1)
+	if err != nil {
+		return err
+	}
+	forkBlock, err := e.commonAncestor(ctx, latestTrace, traces)
+	if err != nil {
+		return err
+	}
+
+	if err := e.models.RollbackAbove(ctx, forkBlock); err != nil {
+		return fmt.Errorf("error rolling back database blocks: %w", err)
+	}
+	e.dispatchReorg(ctx, forkBlock)
+
+	e.logger.Info("Successfully rolled back to common ancestor",
+		"fork_block", forkBlock,
+		"rolled_back_to", latestTrace.BlockNumber,
+		"depth", latestTrace.BlockNumber-forkBlock,
+	)
+	return nil
+}
+
+// commonAncestor 在 latest 之下的历史游标 traces（按区块号降序）中自上而下找到第一个与规范链一致的区块，即共同祖先
+// 超出 MaxReorgDepth 仍未找到时返回 ErrReorgTooDeep；所有游标都在分叉之上时返回最早游标的前一个区块，整段回滚
+func (e *Engine) commonAncestor(ctx context.Context, latest *data.BlockTrace, traces []*data.BlockTrace) (int64, error) {
+	var numbers []int64
+	for _, trace := range traces {
+		if latest.BlockNumber-trace.BlockNumber <= e.config.MaxReorgDepth {
+			numbers = append(numbers, trace.BlockNumber)
+		}
+	}
+	headers, err := e.getCanonicalHeaders(ctx, numbers)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch headers for ancestor search: %w", err)
+	}
+
+	for _, trace := range traces {
+		if latest.BlockNumber-trace.BlockNumber > e.config.MaxReorgDepth {
+			return 0, fmt.Errorf("%w: no common ancestor within %d blocks below %d", ErrReorgTooDeep, e.config.MaxReorgDepth, latest.BlockNumber)
+		}
+
+		if headers[trace.BlockNumber].Hash().Hex() == trace.BlockHash {
+			return trace.BlockNumber, nil
+		}
+	}
+
+	// 数据库中所有游标都在分叉之上：整段回滚，下一轮按冷启动逻辑重新同步
+	forkBlock := latest.BlockNumber - 1
+	if len(traces) > 0 {
+		forkBlock = traces[len(traces)-1].BlockNumber - 1
+	}
+	e.logger.Warn("no common ancestor found in stored traces, rolling back all of them", "fork_block", forkBlock)
+	return forkBlock, nil
+}
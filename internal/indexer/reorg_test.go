@@ -0,0 +1,147 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/chaintest"
+	"github.com/zy99978455-otw/flash-monitor/internal/data"
+	"github.com/zy99978455-otw/flash-monitor/internal/rpc"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// testdata/reorg.json 录制了区块 100-108 的规范区块头；数据库中 105-108 的游标来自已被重组掉的分叉，
+// 引擎应回滚到共同祖先 104，并删除其上的事件
+func TestHandleReorg(t *testing.T) {
+	db := chaintest.OpenDB(t)
+	models := data.NewModels(db)
+	ctx := context.Background()
+
+	m, replayer := replayManager(t, "reorg")
+	e := NewEngine(m, models, testLogger(), Config{})
+
+	// 回放器对同一请求重复返回最后一次录制结果，这里先取出规范哈希用于构造数据库状态
+	canonical, err := e.getCanonicalHeaders(ctx, []int64{107, 106, 105, 104, 103, 102, 101, 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for n := int64(100); n <= 108; n++ {
+		trace := &data.BlockTrace{BlockNumber: n}
+		if n <= 104 {
+			trace.BlockHash = canonical[n].Hash().Hex()
+			trace.ParentHash = canonical[n].ParentHash.Hex()
+			trace.BlockTime = blockTime(canonical[n].Time)
+		} else {
+			trace.BlockHash = common.BigToHash(big.NewInt(0xdead + n)).Hex()
+			trace.ParentHash = common.Hash{}.Hex()
+		}
+		if err := models.BlockTraces.Insert(trace); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, n := range []int64{103, 106} {
+		err := models.TransferEvents.Insert(&data.TransferEvent{
+			TxHash:       common.BigToHash(big.NewInt(n)).Hex(),
+			BlockNumber:  n,
+			BlockHash:    common.Hash{}.Hex(),
+			FromAddress:  common.Address{}.Hex(),
+			ToAddress:    common.Address{}.Hex(),
+			Amount:       "1",
+			TokenAddress: common.Address{}.Hex(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.handleReorg(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	latest, err := models.BlockTraces.GetLatest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest == nil || latest.BlockNumber != 104 {
+		t.Fatalf("latest trace after reorg = %v, want block 104", latest)
+	}
+
+	for query, want := range map[string]int{
+		`SELECT count(*) FROM transfer_events WHERE block_number <= 104`: 1,
+		`SELECT count(*) FROM transfer_events WHERE block_number > 104`:  0,
+	} {
+		var got int
+		if err := db.QueryRow(query).Scan(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s = %d, want %d", query, got, want)
+		}
+	}
+
+	if pending := replayer.Pending(); len(pending) != 0 {
+		t.Errorf("recorded rpc calls never made: %v", pending)
+	}
+}
+
+// traceOf 把模拟链上的区块头转成引擎写入的游标
+func traceOf(h *types.Header) *data.BlockTrace {
+	return &data.BlockTrace{BlockNumber: h.Number.Int64(), BlockHash: h.Hash().Hex(), ParentHash: h.ParentHash.Hex()}
+}
+
+// TestCommonAncestor 不依赖数据库：游标直接取自重组前的链，节点跟随重组后的链
+func TestCommonAncestor(t *testing.T) {
+	chain := chaintest.NewChain()
+	chain.MineEmpty(20)
+	stale := chain.Clone()
+	chain.Fork(3)
+	chain.MineEmpty(3)
+
+	// below 返回 stale 上 [from, to] 的游标，按区块号降序排列，与 BlockTraces.GetBelow 一致
+	below := func(to, from uint64) []*data.BlockTrace {
+		var traces []*data.BlockTrace
+		for n := to; n >= from; n-- {
+			traces = append(traces, traceOf(stale.Header(n)))
+		}
+		return traces
+	}
+
+	tests := []struct {
+		name     string
+		maxDepth int64
+		traces   []*data.BlockTrace
+		want     int64
+		wantErr  error
+	}{
+		{"ancestor below the fork", 0, below(19, 10), 17, nil},
+		{"every trace forked", 0, below(19, 18), 17, nil},
+		{"no stored traces", 0, nil, 19, nil},
+		{"deeper than max depth", 2, below(19, 10), 0, ErrReorgTooDeep},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := rpc.NewManager([]rpc.NodeConfig{{Name: "node0", URL: chaintest.NewNode(t, chain).URL(), Timeout: time.Second}}, rpc.Options{}, testLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(m.Stop)
+			e := NewEngine(m, data.Models{}, testLogger(), Config{MaxReorgDepth: tt.maxDepth})
+
+			got, err := e.commonAncestor(context.Background(), traceOf(stale.Header(20)), tt.traces)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("commonAncestor() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("commonAncestor() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package indexer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/chaintest"
+	"github.com/zy99978455-otw/flash-monitor/internal/data"
+	"github.com/zy99978455-otw/flash-monitor/internal/rpc"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// simEngine 创建连接模拟节点与测试库的引擎，nodes 按优先级排列；监控 USDT，巨鲸阈值 1000 USDT
+func simEngine(t *testing.T, cfg Config, nodes ...*chaintest.Node) (*Engine, *sql.DB) {
+	t.Helper()
+
+	db := chaintest.OpenDB(t)
+	models := data.NewModels(db)
+	if err := models.Tokens.Upsert(&data.Token{Address: chaintest.USDT.Hex(), Symbol: "USDT", Decimals: 6, WhaleThreshold: "1000"}); err != nil {
+		t.Fatal(err)
+	}
+
+	configs := make([]rpc.NodeConfig, len(nodes))
+	for i, node := range nodes {
+		configs[i] = rpc.NodeConfig{Name: fmt.Sprintf("node%d", i), URL: node.URL(), Priority: i, Timeout: time.Second}
+	}
+	m, err := rpc.NewManager(configs, rpc.Options{}, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Stop)
+
+	return NewEngine(m, models, testLogger(), cfg, NewPostgresWriter(models)), db
+}
+
+// usdt 构造一笔 amount USDT 的转账
+func usdt(amount int64) chaintest.Transfer {
+	return chaintest.Transfer{
+		Token:  chaintest.USDT,
+		From:   common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		To:     common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Amount: new(big.Int).Mul(big.NewInt(amount), big.NewInt(1_000_000)),
+	}
+}
+
+// indexedTransfers 返回库中的巨鲸转账：区块号 -> 区块哈希
+func indexedTransfers(t *testing.T, db *sql.DB) map[int64]string {
+	t.Helper()
+
+	rows, err := db.Query(`SELECT block_number, block_hash FROM transfer_events`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	transfers := make(map[int64]string)
+	for rows.Next() {
+		var n int64
+		var hash string
+		if err := rows.Scan(&n, &hash); err != nil {
+			t.Fatal(err)
+		}
+		transfers[n] = hash
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return transfers
+}
+
+// assertTraces 检查 [from, to] 的每个区块游标都与模拟链的规范哈希一致
+func assertTraces(t *testing.T, db *sql.DB, chain *chaintest.Chain, from, to int64) {
+	t.Helper()
+
+	traces, err := data.NewModels(db).BlockTraces.GetBelow(to+1, int(to-from+1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) != int(to-from+1) {
+		t.Fatalf("got %d block traces below %d, want %d", len(traces), to+1, to-from+1)
+	}
+	for _, trace := range traces {
+		if want := chain.Header(uint64(trace.BlockNumber)).Hash().Hex(); trace.BlockHash != want {
+			t.Errorf("trace %d hash = %s, want canonical %s", trace.BlockNumber, trace.BlockHash, want)
+		}
+	}
+}
+
+func TestSyncBlocksFollowsReorg(t *testing.T) {
+	chain := chaintest.NewChain()
+	chain.MineEmpty(10)
+
+	e, db := simEngine(t, Config{}, chaintest.NewNode(t, chain))
+	ctx := context.Background()
+
+	// 冷启动从链头下方 5 个区块开始
+	if err := e.syncBlocks(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertTraces(t, db, chain, 6, 10)
+
+	chain.Mine()
+	whale := chain.Mine(usdt(5_000)) // 12
+	chain.Mine(usdt(10))             // 13：低于阈值
+	orphaned := chain.Mine(usdt(2_000))
+	chain.Mine()
+
+	if err := e.syncBlocks(ctx); err != nil {
+		t.Fatal(err)
+	}
+	got := indexedTransfers(t, db)
+	if len(got) != 2 || got[12] != whale.Hash().Hex() || got[14] != orphaned.Hash().Hex() {
+		t.Fatalf("transfers before reorg = %v, want blocks 12 and 14", got)
+	}
+
+	// 13-15 被重组替换：原 14 的转账不再存在，新的 14 打包了另一笔巨鲸转账
+	chain.Fork(3)
+	chain.Mine()
+	replaced := chain.Mine(usdt(3_000))
+	chain.Mine()
+
+	if err := e.syncBlocks(ctx); err != nil {
+		t.Fatal(err)
+	}
+	got = indexedTransfers(t, db)
+	if len(got) != 2 || got[12] != whale.Hash().Hex() || got[14] != replaced.Hash().Hex() {
+		t.Fatalf("transfers after reorg = %v, want block 12 and the replacement block 14 %s", got, replaced.Hash().Hex())
+	}
+	assertTraces(t, db, chain, 6, 15)
+}
+
+func TestSyncBlocksHaltsOnDeepReorg(t *testing.T) {
+	chain := chaintest.NewChain()
+	chain.MineEmpty(10)
+
+	e, db := simEngine(t, Config{MaxReorgDepth: 2}, chaintest.NewNode(t, chain))
+	ctx := context.Background()
+
+	// 深度 2 时每轮只记录链头附近的游标：第一轮 9-10，第二轮 12-13
+	if err := e.syncBlocks(ctx); err != nil {
+		t.Fatal(err)
+	}
+	chain.MineEmpty(3)
+	if err := e.syncBlocks(ctx); err != nil {
+		t.Fatal(err)
+	}
+	stale := chain.Header(13).Hash().Hex()
+
+	chain.Fork(5)
+	chain.MineEmpty(5)
+
+	err := e.syncBlocks(ctx)
+	if !errors.Is(err, ErrReorgTooDeep) {
+		t.Fatalf("syncBlocks() error = %v, want ErrReorgTooDeep", err)
+	}
+
+	// 超过深度时不自动回滚，保留现场等待人工处理
+	latest, err := data.NewModels(db).BlockTraces.GetLatest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.BlockNumber != 13 || latest.BlockHash != stale {
+		t.Errorf("latest trace = %d %s, want untouched 13 %s", latest.BlockNumber, latest.BlockHash, stale)
+	}
+}
+
+func TestSyncBlocksFailsOver(t *testing.T) {
+	chain := chaintest.NewChain()
+	chain.MineEmpty(10)
+
+	primary, backup := chaintest.NewNode(t, chain), chaintest.NewNode(t, chain)
+	e, db := simEngine(t, Config{}, primary, backup)
+	primary.SetFault(chaintest.FaultRateLimit)
+
+	block := chain.Mine(usdt(1_000_000))
+	chain.MineEmpty(2)
+
+	if err := e.syncBlocks(context.Background()); err != nil {
+		t.Fatalf("sync failed with a healthy backup: %v", err)
+	}
+	if got := indexedTransfers(t, db); got[block.Number.Int64()] != block.Hash().Hex() {
+		t.Errorf("transfers = %v, want whale transfer in block %d", got, block.Number)
+	}
+	if primary.Requests() == 0 || backup.Calls("eth_getLogs") == 0 {
+		t.Errorf("primary requests = %d, backup eth_getLogs = %d; want the backup to take over", primary.Requests(), backup.Calls("eth_getLogs"))
+	}
+}
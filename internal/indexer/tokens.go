@@ -0,0 +1,108 @@
+package indexer
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/data"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// watchedToken 是引擎内部使用的代币视图，巨鲸阈值已换算为链上最小单位
+type watchedToken struct {
+	address   common.Address
+	symbol    string
+	decimals  int
+	threshold *big.Int
+}
+
+// TokenRegistry 监控代币注册表：按合约地址索引，供构建过滤条件与巨鲸过滤使用
+type TokenRegistry struct {
+	tokens map[common.Address]*watchedToken
+	order  []common.Address
+}
+
+// NewTokenRegistry 根据数据库中的代币列表构建注册表
+func NewTokenRegistry(tokens []*data.Token) (*TokenRegistry, error) {
+	r := &TokenRegistry{
+		tokens: make(map[common.Address]*watchedToken, len(tokens)),
+	}
+
+	for _, t := range tokens {
+		if !common.IsHexAddress(t.Address) {
+			return nil, fmt.Errorf("token %s: invalid contract address %q", t.Symbol, t.Address)
+		}
+
+		threshold, err := toBaseUnits(t.WhaleThreshold, t.Decimals)
+		if err != nil {
+			return nil, fmt.Errorf("token %s: %w", t.Symbol, err)
+		}
+
+		addr := common.HexToAddress(t.Address)
+		if _, exists := r.tokens[addr]; !exists {
+			r.order = append(r.order, addr)
+		}
+		r.tokens[addr] = &watchedToken{
+			address:   addr,
+			symbol:    t.Symbol,
+			decimals:  t.Decimals,
+			threshold: threshold,
+		}
+	}
+
+	return r, nil
+}
+
+// Addresses 返回全部监控合约地址，顺序与注册顺序一致
+func (r *TokenRegistry) Addresses() []common.Address {
+	addrs := make([]common.Address, len(r.order))
+	copy(addrs, r.order)
+	return addrs
+}
+
+// Len 返回注册表中的代币数量
+func (r *TokenRegistry) Len() int {
+	return len(r.order)
+}
+
+// Contains 判断合约是否在监控列表中
+func (r *TokenRegistry) Contains(token common.Address) bool {
+	_, ok := r.tokens[token]
+	return ok
+}
+
+// IsWhale 判断某合约的一笔转账是否达到该代币的巨鲸阈值
+// 未注册的合约一律返回 false
+func (r *TokenRegistry) IsWhale(token common.Address, amount *big.Int) bool {
+	t, ok := r.tokens[token]
+	if !ok {
+		return false
+	}
+	return amount.Cmp(t.threshold) >= 0
+}
+
+// Describe 返回代币符号以及按 decimals 换算后的可读金额，未注册的合约返回空字符串
+func (r *TokenRegistry) Describe(token common.Address, amount *big.Int) (symbol, formatted string) {
+	t, ok := r.tokens[token]
+	if !ok {
+		return "", ""
+	}
+	return t.symbol, data.FormatUnits(amount.String(), t.decimals)
+}
+
+// toBaseUnits 把以整数单位书写的阈值（允许小数，如 "0.5"）换算成链上最小单位，不足 1 的部分向下取整
+func toBaseUnits(amount string, decimals int) (*big.Int, error) {
+	value, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		return nil, fmt.Errorf("invalid whale threshold %q", amount)
+	}
+	if value.Sign() < 0 {
+		return nil, fmt.Errorf("whale threshold must not be negative: %q", amount)
+	}
+
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	value.Mul(value, new(big.Rat).SetInt(scale))
+
+	return new(big.Int).Quo(value.Num(), value.Denom()), nil
+}
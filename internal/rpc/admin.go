@@ -0,0 +1,208 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+)
+
+// 运行时节点管理相关错误
+var (
+	ErrNodeNotFound  = errors.New("rpc node not found")
+	ErrDuplicateNode = errors.New("rpc node with this name already exists")
+	ErrLastNode      = errors.New("cannot remove the last rpc node")
+	ErrBelowMinNodes = errors.New("too few rpc nodes for the configured quorum")
+	ErrChainMismatch = errors.New("rpc node is on a different chain")
+)
+
+// drainPollInterval 摘除节点时检查在途请求是否结束的间隔
+const drainPollInterval = 100 * time.Millisecond
+
+// NodeUpdate 运行时可修改的节点参数，nil 字段保持不变
+type NodeUpdate struct {
+	Priority *int
+	Weight   *int
+}
+
+// Nodes 返回全部节点（包括不健康的），按注册顺序排列
+func (m *Manager) Nodes() []*Node {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.Clone(m.nodes)
+}
+
+// Node 按名称查找节点
+func (m *Manager) Node(name string) (*Node, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, node := range m.nodes {
+		if node.Config.Name == name {
+			return node, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, name)
+}
+
+// AddNode 在运行时注册一个新节点
+// 新节点的 eth_chainId 必须与现有节点一致，并先做一次健康检查，
+// 连不上或返回错误（如密钥无效）时不会加入，避免把请求路由到坏节点
+func (m *Manager) AddNode(config NodeConfig) (*Node, error) {
+	if _, err := m.Node(config.Name); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateNode, config.Name)
+	}
+
+	node, err := m.createNode(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.checkChainID(node); err != nil {
+		node.Client.Close()
+		return nil, err
+	}
+
+	m.checkNodeHealth(node)
+	if err := node.StatusSnapshot().LastError; err != nil {
+		node.Client.Close()
+		return nil, fmt.Errorf("initial health check failed: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 健康检查期间可能有同名节点被并发加入
+	if slices.ContainsFunc(m.nodes, func(n *Node) bool { return n.Config.Name == config.Name }) {
+		node.Client.Close()
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateNode, config.Name)
+	}
+	m.nodes = append(m.nodes, node)
+
+	m.logger.Info("rpc node added", "name", node.Config.Name, "priority", node.Config.Priority, "weight", node.Config.Weight)
+	return node, nil
+}
+
+// checkChainID 确认新节点与现有节点在同一条链上，避免把主网请求路由到测试网节点
+func (m *Manager) checkChainID(node *Node) error {
+	ctx, cancel := context.WithTimeout(m.ctx, node.Config.Timeout)
+	defer cancel()
+
+	want, err := m.chainID(ctx)
+	if err != nil {
+		return fmt.Errorf("query chain id of existing nodes: %w", err)
+	}
+	got, err := node.Client.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("query chain id: %w", err)
+	}
+	if got.Cmp(want) != 0 {
+		return fmt.Errorf("%w: chain id %s, existing nodes are on %s", ErrChainMismatch, got, want)
+	}
+	return nil
+}
+
+// chainID 返回现有节点所在链的 ID，首次调用时经由健康节点查询，之后使用缓存
+func (m *Manager) chainID(ctx context.Context) (*big.Int, error) {
+	m.chainIDMu.Lock()
+	defer m.chainIDMu.Unlock()
+
+	if m.knownChainID != nil {
+		return m.knownChainID, nil
+	}
+
+	var id *big.Int
+	err := m.ExecuteContext(ctx, func(ctx context.Context, node *Node) error {
+		var err error
+		id, err = node.Client.ChainID(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.knownChainID = id
+	return id, nil
+}
+
+// RemoveNode 摘除节点：立即停止向它分配新请求，等待在途请求结束（或 ctx 到期）后关闭连接
+// 剩余节点数不能低于 Options.MinNodes，否则 quorum 调用将无法达成
+func (m *Manager) RemoveNode(ctx context.Context, name string) error {
+	m.mu.Lock()
+	i := slices.IndexFunc(m.nodes, func(n *Node) bool { return n.Config.Name == name })
+	if i < 0 {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrNodeNotFound, name)
+	}
+	if len(m.nodes) == 1 {
+		m.mu.Unlock()
+		return ErrLastNode
+	}
+	if len(m.nodes)-1 < m.options.MinNodes {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: %d nodes left after removing %s, need at least %d", ErrBelowMinNodes, len(m.nodes)-1, name, m.options.MinNodes)
+	}
+	node := m.nodes[i]
+	// 复制后再删除，健康检查等持有旧切片的协程不受影响
+	m.nodes = slices.Delete(slices.Clone(m.nodes), i, i+1)
+	m.mu.Unlock()
+
+	m.weightMu.Lock()
+	delete(m.currentWeights, node)
+	m.weightMu.Unlock()
+
+	// 链头不再计入被摘除节点报告的高度
+	m.refreshBestHead()
+
+	m.logger.Info("draining rpc node", "name", name, "in_flight", node.inflight.Load())
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for node.inflight.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			m.logger.Warn("drain timed out, closing rpc node with requests in flight", "name", name, "in_flight", node.inflight.Load())
+			node.Client.Close()
+			return nil
+		case <-ticker.C:
+		}
+	}
+
+	node.Client.Close()
+	m.logger.Info("rpc node removed", "name", name)
+	return nil
+}
+
+// UpdateNode 修改节点的优先级与权重，下一次选择节点时生效
+func (m *Manager) UpdateNode(name string, update NodeUpdate) (*Node, error) {
+	node, err := m.Node(name)
+	if err != nil {
+		return nil, err
+	}
+
+	node.mu.Lock()
+	if update.Priority != nil {
+		node.Config.Priority = *update.Priority
+	}
+	if update.Weight != nil {
+		node.Config.Weight = max(*update.Weight, 1)
+	}
+	priority, weight := node.Config.Priority, node.Config.Weight
+	node.mu.Unlock()
+
+	m.logger.Info("rpc node updated", "name", name, "priority", priority, "weight", weight)
+	return node, nil
+}
+
+// CheckNode 立即对节点执行一次健康检查并返回最新状态，不必等待下一轮定时检查
+func (m *Manager) CheckNode(name string) (NodeStatus, error) {
+	node, err := m.Node(name)
+	if err != nil {
+		return NodeStatus{}, err
+	}
+
+	m.checkNodeHealth(node)
+	return node.StatusSnapshot(), nil
+}
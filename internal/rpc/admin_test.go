@@ -0,0 +1,64 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/chaintest"
+)
+
+func TestRemoveNodeKeepsMinNodes(t *testing.T) {
+	chain := chaintest.NewChain()
+	chain.MineEmpty(10)
+
+	m := newSimManager(t, Options{MinNodes: 2}, chaintest.NewNode(t, chain), chaintest.NewNode(t, chain), chaintest.NewNode(t, chain))
+
+	if err := m.RemoveNode(context.Background(), "node2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RemoveNode(context.Background(), "node1"); !errors.Is(err, ErrBelowMinNodes) {
+		t.Fatalf("RemoveNode below the quorum: err = %v, want ErrBelowMinNodes", err)
+	}
+	if n := len(m.Nodes()); n != 2 {
+		t.Errorf("nodes after refused removal = %d, want 2", n)
+	}
+}
+
+func TestNewManagerRequiresMinNodes(t *testing.T) {
+	chain := chaintest.NewChain()
+
+	_, err := NewManager(
+		[]NodeConfig{{Name: "only", URL: chaintest.NewNode(t, chain).URL()}},
+		Options{MinNodes: 2},
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+	if !errors.Is(err, ErrBelowMinNodes) {
+		t.Fatalf("NewManager with fewer nodes than MinNodes: err = %v, want ErrBelowMinNodes", err)
+	}
+}
+
+func TestAddNodeChecksChainID(t *testing.T) {
+	chain := chaintest.NewChain()
+	chain.MineEmpty(10)
+
+	m := newSimManager(t, Options{}, chaintest.NewNode(t, chain))
+
+	other := chaintest.NewNode(t, chain)
+	other.SetChainID(11155111)
+	if _, err := m.AddNode(NodeConfig{Name: "sepolia", URL: other.URL()}); !errors.Is(err, ErrChainMismatch) {
+		t.Fatalf("AddNode on another chain: err = %v, want ErrChainMismatch", err)
+	}
+	if other.Calls("eth_blockNumber") != 0 {
+		t.Error("node on another chain was health checked before being rejected")
+	}
+
+	if _, err := m.AddNode(NodeConfig{Name: "mainnet", URL: chaintest.NewNode(t, chain).URL()}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(m.Nodes()); n != 2 {
+		t.Errorf("nodes = %d, want 2", n)
+	}
+}
@@ -0,0 +1,75 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxBatchSize 单个 JSON-RPC 批量请求最多包含的调用数；多数服务商限制在 100~1000 之间
+const maxBatchSize = 100
+
+type callsKey struct{}
+
+// WithCalls 声明 ctx 上的每次尝试实际包含 calls 个 JSON-RPC 调用（批量请求）。
+// 服务商按调用数计费，节点管理器据此扣减当天额度与令牌；直接使用 Node.BatchCall 的调用方
+// （如在 Quorum 中批量查询）需要自行声明
+func WithCalls(ctx context.Context, calls int) context.Context {
+	return context.WithValue(ctx, callsKey{}, calls)
+}
+
+// callsFrom 返回 ctx 上声明的调用数，未声明时为 1
+func callsFrom(ctx context.Context) int {
+	if calls, ok := ctx.Value(callsKey{}).(int); ok && calls > 0 {
+		return calls
+	}
+	return 1
+}
+
+// BatchCall 以 JSON-RPC 批量请求执行 elems，超过 maxBatchSize 时自动分段，每段都经过节点选择、熔断与重试。
+// 传输层失败或某个调用返回可重试的错误（限流、节点数据落后）时整段换节点重试；
+// 确定性错误（revert、参数错误等）保留在对应 BatchElem.Error 中交给调用方处理；
+// 每段按其中的调用数扣减节点额度
+func (m *Manager) BatchCall(ctx context.Context, elems []ethrpc.BatchElem) error {
+	for start := 0; start < len(elems); start += maxBatchSize {
+		chunk := elems[start:min(start+maxBatchSize, len(elems))]
+
+		err := m.ExecuteContext(WithCalls(ctx, len(chunk)), func(ctx context.Context, node *Node) error {
+			return node.BatchCall(ctx, chunk)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BatchCall 在当前节点上以批量请求执行 elems，超过 maxBatchSize 时分段发送，不做重试与故障转移。
+// 可重试的调用错误会合并返回，便于调用方（如 ExecuteContext、Quorum）按节点故障处理
+func (n *Node) BatchCall(ctx context.Context, elems []ethrpc.BatchElem) error {
+	for start := 0; start < len(elems); start += maxBatchSize {
+		chunk := elems[start:min(start+maxBatchSize, len(elems))]
+		for i := range chunk {
+			chunk[i].Error = nil
+		}
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, n.Config.Timeout)
+		err := n.RPCClient.BatchCallContext(timeoutCtx, chunk)
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		var elemErrs []error
+		for _, elem := range chunk {
+			if elem.Error != nil && Classify(elem.Error).Retryable() {
+				elemErrs = append(elemErrs, elem.Error)
+			}
+		}
+		if err := errors.Join(elemErrs...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/chaintest"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+)
+
+// headerBatch 构造查询区块 0..n-1 的批量请求
+func headerBatch(n int) []ethrpc.BatchElem {
+	elems := make([]ethrpc.BatchElem, n)
+	for i := range elems {
+		elems[i] = ethrpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []any{hexutil.EncodeUint64(uint64(i)), false},
+			Result: new(map[string]any),
+		}
+	}
+	return elems
+}
+
+func TestBatchCallChargesEveryCall(t *testing.T) {
+	chain := chaintest.NewChain()
+	chain.MineEmpty(200)
+
+	m, err := NewManager(
+		[]NodeConfig{{Name: "metered", URL: chaintest.NewNode(t, chain).URL(), DailyLimit: 1000, RateLimit: 1000, Burst: 50}},
+		Options{},
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Stop()
+
+	// 150 个调用分两段发送，额度按调用数而不是 HTTP 请求数扣减；批量大于桶容量时分次等待令牌
+	if err := m.BatchCall(context.Background(), headerBatch(150)); err != nil {
+		t.Fatal(err)
+	}
+	node, _ := m.Node("metered")
+	if used := node.StatusSnapshot().DailyRequests; used != 150 {
+		t.Errorf("daily requests after a 150-call batch = %d, want 150", used)
+	}
+
+	// 剩余额度不足以发出整个批量请求时不发送
+	if err := m.BatchCall(context.Background(), headerBatch(900)); err == nil {
+		t.Error("batch exceeding the remaining daily budget was sent")
+	}
+}
+
+func TestQuorumBatchChargesEveryCall(t *testing.T) {
+	chain := chaintest.NewChain()
+	chain.MineEmpty(10)
+
+	m := newSimManager(t, Options{}, chaintest.NewNode(t, chain), chaintest.NewNode(t, chain))
+
+	_, err := Quorum(WithCalls(context.Background(), 5), m, 2, func(ctx context.Context, node *Node) (int, error) {
+		return 5, node.BatchCall(ctx, headerBatch(5))
+	}, func(n int) string { return fmt.Sprint(n) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range m.Nodes() {
+		if used := node.StatusSnapshot().DailyRequests; used != 5 {
+			t.Errorf("%s daily requests = %d, want 5", node.Config.Name, used)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package rpc
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrCircuitOpen 节点熔断器处于打开状态（或半开探测名额已用完），请求未发出
+var ErrCircuitOpen = errors.New("node circuit breaker is open")
+
+// 熔断器状态
+const (
+	CircuitClosed   = "closed"    // 正常放行，统计失败率
+	CircuitOpen     = "open"      // 拒绝所有请求，冷却期结束后进入半开
+	CircuitHalfOpen = "half-open" // 放行少量探测请求，全部成功则关闭，任一失败则重新打开
+)
+
+// BreakerConfig 熔断器参数，零值字段使用默认值
+type BreakerConfig struct {
+	// ConsecutiveFailures 连续失败达到该次数立即熔断
+	ConsecutiveFailures int
+	// FailureRate 滑动窗口内失败率达到该比例时熔断（0~1）
+	FailureRate float64
+	// WindowSize 统计失败率的滑动窗口大小（最近 N 次请求）
+	WindowSize int
+	// MinRequests 窗口内至少有这么多次请求才按失败率判断，避免样本太少误判
+	MinRequests int
+	// Cooldown 熔断后等待多久进入半开状态
+	Cooldown time.Duration
+	// HalfOpenProbes 半开状态下放行的探测请求数，全部成功才恢复
+	HalfOpenProbes int
+}
+
+func (c BreakerConfig) withDefaults() BreakerConfig {
+	if c.ConsecutiveFailures <= 0 {
+		c.ConsecutiveFailures = 3
+	}
+	if c.FailureRate <= 0 {
+		c.FailureRate = 0.5
+	}
+	if c.WindowSize <= 0 {
+		c.WindowSize = 20
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = max(c.WindowSize/2, 1)
+	}
+	if c.Cooldown <= 0 {
+		c.Cooldown = 30 * time.Second
+	}
+	if c.HalfOpenProbes <= 0 {
+		c.HalfOpenProbes = 1
+	}
+	return c
+}
+
+// breaker 单个节点的熔断器；所有方法都要求调用方持有所属 Node 的 mu 写锁
+type breaker struct {
+	config BreakerConfig
+
+	state    string
+	openedAt time.Time
+
+	// 关闭状态下的滑动窗口（环形缓冲区，true 表示失败）
+	outcomes    []bool
+	next        int
+	filled      int
+	failures    int
+	consecutive int
+
+	// 半开状态下已放行与已成功的探测数
+	probes         int
+	probeSuccesses int
+}
+
+func newBreaker(config BreakerConfig) *breaker {
+	config = config.withDefaults()
+	return &breaker{
+		config:   config,
+		state:    CircuitClosed,
+		outcomes: make([]bool, config.WindowSize),
+	}
+}
+
+// available 判断节点当前能否接收请求，不占用半开探测名额
+func (b *breaker) available(now time.Time) bool {
+	switch b.state {
+	case CircuitOpen:
+		return now.Sub(b.openedAt) >= b.config.Cooldown
+	case CircuitHalfOpen:
+		return b.probes < b.config.HalfOpenProbes
+	default:
+		return true
+	}
+}
+
+// allow 在真正发出请求前调用；冷却期结束时转入半开并占用一个探测名额
+func (b *breaker) allow(now time.Time) bool {
+	if b.state == CircuitOpen && now.Sub(b.openedAt) >= b.config.Cooldown {
+		b.state = CircuitHalfOpen
+		b.probes = 0
+		b.probeSuccesses = 0
+	}
+
+	switch b.state {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		if b.probes >= b.config.HalfOpenProbes {
+			return false
+		}
+		b.probes++
+		return true
+	default:
+		return true
+	}
+}
+
+// release 归还一次未产生结果的请求（如调用方取消），避免半开探测名额被永久占用
+func (b *breaker) release() {
+	if b.state == CircuitHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// record 记录一次请求结果，返回状态是否发生了变化
+func (b *breaker) record(failed bool, now time.Time) bool {
+	switch b.state {
+	case CircuitHalfOpen:
+		if failed {
+			b.trip(now)
+			return true
+		}
+		b.probeSuccesses++
+		if b.probeSuccesses >= b.config.HalfOpenProbes {
+			b.reset()
+			return true
+		}
+		return false
+
+	case CircuitClosed:
+		if b.filled == len(b.outcomes) && b.outcomes[b.next] {
+			b.failures--
+		}
+		b.outcomes[b.next] = failed
+		b.next = (b.next + 1) % len(b.outcomes)
+		b.filled = min(b.filled+1, len(b.outcomes))
+
+		if failed {
+			b.failures++
+			b.consecutive++
+		} else {
+			b.consecutive = 0
+		}
+
+		if b.consecutive >= b.config.ConsecutiveFailures ||
+			(b.filled >= b.config.MinRequests && float64(b.failures)/float64(b.filled) >= b.config.FailureRate) {
+			b.trip(now)
+			return true
+		}
+		return false
+
+	default:
+		// 熔断前已经发出的请求，结果不再影响状态
+		return false
+	}
+}
+
+func (b *breaker) trip(now time.Time) {
+	b.state = CircuitOpen
+	b.openedAt = now
+}
+
+// reset 恢复到关闭状态并清空统计窗口
+func (b *breaker) reset() {
+	b.state = CircuitClosed
+	clear(b.outcomes)
+	b.next, b.filled, b.failures, b.consecutive = 0, 0, 0, 0
+}
@@ -0,0 +1,141 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrBudgetExhausted 节点当天的请求额度已用完
+var ErrBudgetExhausted = errors.New("node daily request budget exhausted")
+
+// budgetWarnRatio 当天额度用到该比例时打印一次告警
+const budgetWarnRatio = 0.9
+
+// budget 单个节点的客户端限流：令牌桶控制 RPS，另有可选的按 UTC 自然日计的请求上限
+// limiter 自带锁；其余字段受所属 Node 的 mu 保护
+type budget struct {
+	limiter    *rate.Limiter // 为 nil 表示不限速
+	dailyLimit int64         // 0 表示不限
+	day        int           // 当前计数对应的日期（yyyymmdd，UTC）
+	used       int64
+	warned     bool
+}
+
+func newBudget(config NodeConfig) *budget {
+	b := &budget{dailyLimit: config.DailyLimit}
+	if config.RateLimit > 0 {
+		burst := config.Burst
+		if burst <= 0 {
+			burst = max(int(config.RateLimit), 1)
+		}
+		b.limiter = rate.NewLimiter(rate.Limit(config.RateLimit), burst)
+	}
+	return b
+}
+
+func dayOf(t time.Time) int {
+	y, m, d := t.UTC().Date()
+	return y*10000 + int(m)*100 + d
+}
+
+// rollover 跨过 UTC 零点时清零当天计数，调用方需持有 node.mu 写锁
+func (b *budget) rollover(now time.Time) {
+	if today := dayOf(now); today != b.day {
+		b.day = today
+		b.used = 0
+		b.warned = false
+	}
+}
+
+// remaining 当天剩余额度，-1 表示不限
+func (b *budget) remaining() int64 {
+	if b.dailyLimit == 0 {
+		return -1
+	}
+	return max(b.dailyLimit-b.used, 0)
+}
+
+// exhausted 当天额度是否已用完，只读，调用方需至少持有 node.mu 读锁
+func (b *budget) exhausted(now time.Time) bool {
+	return b.dailyLimit > 0 && dayOf(now) == b.day && b.used >= b.dailyLimit
+}
+
+// throttled 令牌桶当前是否没有可用令牌（此时请求需要排队）
+func (b *budget) throttled(now time.Time) bool {
+	return b.limiter != nil && b.limiter.TokensAt(now) < 1
+}
+
+// countRequests 记录 calls 次调用并刷新节点状态中的额度信息，剩余额度不足时返回 ErrBudgetExhausted
+// 服务商按批量请求中的调用数计费，批量请求需按元素个数计入
+func (n *Node) countRequests(now time.Time, calls int) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	b := n.budget
+	b.rollover(now)
+	if b.dailyLimit > 0 && b.used+int64(calls) > b.dailyLimit {
+		return ErrBudgetExhausted
+	}
+	b.used += int64(calls)
+
+	n.Status.DailyRequests = b.used
+	n.Status.DailyRemaining = b.remaining()
+	return nil
+}
+
+// acquireN 在发出包含 calls 个调用的请求前占用额度：先扣减当天额度，再按令牌桶排队等待（ctx 取消时立即返回）
+func (n *Node) acquireN(ctx context.Context, m *Manager, calls int) error {
+	now := time.Now()
+	if err := n.countRequests(now, calls); err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	b := n.budget
+	if b.dailyLimit > 0 && !b.warned && float64(b.used) >= float64(b.dailyLimit)*budgetWarnRatio {
+		b.warned = true
+		m.logger.Warn("node daily request budget almost exhausted",
+			"name", n.Config.Name,
+			"used", b.used,
+			"limit", b.dailyLimit)
+	}
+	n.mu.Unlock()
+
+	if b.limiter == nil {
+		return nil
+	}
+	// WaitN 一次不能超过桶容量，大批量请求分几次等待令牌
+	for calls > 0 {
+		k := min(calls, b.limiter.Burst())
+		if err := b.limiter.WaitN(ctx, k); err != nil {
+			return err
+		}
+		calls -= k
+	}
+	return nil
+}
+
+// StatusSnapshot 返回节点状态的副本，额度相关字段按当前时刻刷新
+func (n *Node) StatusSnapshot() NodeStatus {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	now := time.Now()
+	status := n.Status
+	status.DailyRequests = n.budget.used
+	status.DailyRemaining = n.budget.remaining()
+	if n.budget.day != dayOf(now) {
+		status.DailyRequests = 0
+		if n.budget.dailyLimit > 0 {
+			status.DailyRemaining = n.budget.dailyLimit
+		}
+	}
+	status.RateTokens = -1
+	if n.budget.limiter != nil {
+		status.RateTokens = n.budget.limiter.TokensAt(now)
+	}
+	return status
+}
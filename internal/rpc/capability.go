@@ -0,0 +1,65 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+)
+
+// Capabilities 节点声明的能力，决定哪些请求可以路由到它
+type Capabilities struct {
+	// Archive 归档节点：保留全部历史状态与区块头，可查询任意高度；裁剪过的全节点查询旧区块会报 missing trie node / header not found
+	Archive bool
+	// Traces 支持 debug_* / trace_* 命名空间
+	Traces bool
+	// WebSocket 支持 eth_subscribe，ws/wss 节点自动具备
+	WebSocket bool
+	// MaxLogRange 服务商允许单次 eth_getLogs 查询的最大区块跨度，0 表示未知
+	MaxLogRange int64
+}
+
+// Requirements 一次调用对节点能力的要求，通过 WithRequirements 挂在 context 上传给 Manager
+type Requirements struct {
+	Archive   bool
+	Traces    bool
+	WebSocket bool
+	// LogRange 本次 eth_getLogs 希望单次覆盖的区块跨度，只作为偏好：
+	// 优先选择 MaxLogRange 足够的节点，没有时仍可路由到其它节点分段查询
+	LogRange int64
+}
+
+type requirementsKey struct{}
+
+// WithRequirements 返回携带节点能力要求的 context；与 ctx 上已有的要求合并
+// ExecuteContext、Hedged、Quorum、BatchCall 等都会按它筛选节点
+func WithRequirements(ctx context.Context, req Requirements) context.Context {
+	prev := requirementsFrom(ctx)
+	req.Archive = req.Archive || prev.Archive
+	req.Traces = req.Traces || prev.Traces
+	req.WebSocket = req.WebSocket || prev.WebSocket
+	req.LogRange = max(req.LogRange, prev.LogRange)
+	return context.WithValue(ctx, requirementsKey{}, req)
+}
+
+func requirementsFrom(ctx context.Context) Requirements {
+	req, _ := ctx.Value(requirementsKey{}).(Requirements)
+	return req
+}
+
+// satisfiedBy 判断节点能力是否满足硬性要求；declared 中没有任何节点声明的能力视为不要求，
+// 未配置能力的部署保持原有行为
+func (r Requirements) satisfiedBy(caps, declared Capabilities) bool {
+	return (!r.Archive || !declared.Archive || caps.Archive) &&
+		(!r.Traces || !declared.Traces || caps.Traces) &&
+		(!r.WebSocket || !declared.WebSocket || caps.WebSocket)
+}
+
+// coversLogRange 节点的 eth_getLogs 跨度上限是否足以一次覆盖 LogRange
+func (r Requirements) coversLogRange(caps Capabilities) bool {
+	return r.LogRange == 0 || caps.MaxLogRange == 0 || caps.MaxLogRange >= r.LogRange
+}
+
+// isWebSocketURL 节点 URL 是否使用 ws/wss 协议
+func isWebSocketURL(u string) bool {
+	u = strings.ToLower(u)
+	return strings.HasPrefix(u, "ws://") || strings.HasPrefix(u, "wss://")
+}
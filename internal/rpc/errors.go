@@ -0,0 +1,228 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum"
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+)
+
+// 各家服务商对 eth_getLogs 结果过多/跨度过大的报错措辞各不相同
+var logRangeErrorMessages = []string{
+	"query returned more than",         // Infura / Geth
+	"too many results",                 // 通用
+	"log response size exceeded",       // Alchemy
+	"exceed maximum block range",       // Ankr 等
+	"block range is too large",         // QuickNode
+	"query exceeds max results",        // Nethermind
+	"response size should not greater", // BSC/部分公共节点
+	"more than 10000 results",          // Infura 旧版文案
+	"eth_getlogs is limited to",        // Cloudflare 等
+	"range too large",                  // Erigon 等
+}
+
+// IsLogRangeError 判断错误是否为 eth_getLogs 请求范围过大
+// 这类错误不代表节点故障，调用方应拆小区块范围重试
+func IsLogRangeError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return containsAny(strings.ToLower(err.Error()), logRangeErrorMessages)
+}
+
+// ErrorClass 节点请求错误的分类，决定是否重试以及是否计入节点故障
+type ErrorClass int
+
+const (
+	classSkipped ErrorClass = iota - 1 // 熔断器拒绝或额度用完，请求没有发出（仅节点管理器内部使用）
+
+	ClassUnknown     // 无法识别，按节点故障处理
+	ClassCanceled    // 调用方取消，立即停止
+	ClassTimeout     // 单次请求超时
+	ClassTransport   // 连接拒绝/重置、DNS、HTTP 5xx 等传输层或服务商故障
+	ClassRateLimited // HTTP 429 或服务商限流
+	ClassStale       // 节点数据落后（header not found、落后链头），换节点重试但不计入故障
+	ClassExecution   // 确定性的 JSON-RPC 错误（revert、参数错误、方法不存在），换节点也没用
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ClassCanceled:
+		return "canceled"
+	case ClassTimeout:
+		return "timeout"
+	case ClassTransport:
+		return "transport"
+	case ClassRateLimited:
+		return "rate_limited"
+	case ClassStale:
+		return "stale"
+	case ClassExecution:
+		return "execution"
+	case classSkipped:
+		return "skipped"
+	default:
+		return "unknown"
+	}
+}
+
+// Retryable 该类错误换节点或稍后重试是否可能成功
+func (c ErrorClass) Retryable() bool {
+	return c != ClassCanceled && c != ClassExecution
+}
+
+// Penalizes 该类错误是否说明节点本身有问题，需要计入熔断器
+func (c ErrorClass) Penalizes() bool {
+	switch c {
+	case ClassTimeout, ClassTransport, ClassRateLimited, ClassUnknown:
+		return true
+	default:
+		return false
+	}
+}
+
+var rateLimitMessages = []string{
+	"rate limit",
+	"too many requests",
+	"exceeded its compute units", // Alchemy
+	"request rate exceeded",      // Infura
+	"daily request count exceeded",
+	"capacity exceeded",
+}
+
+var staleMessages = []string{
+	"header not found",
+	"unknown block",
+	"block not found",
+	"missing trie node",
+}
+
+var transportMessages = []string{
+	"connection refused",
+	"connection reset",
+	"broken pipe",
+	"no such host",
+	"server closed idle connection",
+	"unexpected eof",
+}
+
+// 服务商以 JSON-RPC 错误对象返回的自身故障，换节点可能成功
+var providerFaultMessages = []string{
+	"internal error",
+	"upstream",
+	"bad gateway",
+	"service unavailable",
+	"temporarily unavailable",
+}
+
+var timeoutMessages = []string{
+	"timeout",
+	"timed out",
+}
+
+// 确定性的执行错误：请求本身有问题，任何节点都会给出相同结果
+var executionMessages = []string{
+	"execution reverted",
+	"invalid argument",
+	"invalid params",
+	"does not exist/is not available",
+}
+
+// 确定性错误对应的 JSON-RPC 错误码
+const (
+	codeExecutionReverted = 3
+	codeInvalidParams     = -32602
+	codeMethodNotFound    = -32601
+	codeInternalError     = -32603
+)
+
+// Classify 对节点请求返回的错误分类
+func Classify(err error) ErrorClass {
+	if err == nil {
+		return ClassUnknown
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return ClassCanceled
+	}
+	if errors.Is(err, ErrNodeLagging) || errors.Is(err, ethereum.NotFound) {
+		return ClassStale
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return ClassTimeout
+	}
+
+	var httpErr ethrpc.HTTPError
+	if errors.As(err, &httpErr) {
+		switch {
+		case httpErr.StatusCode == http.StatusTooManyRequests:
+			return ClassRateLimited
+		case httpErr.StatusCode >= 500:
+			return ClassTransport
+		}
+		return ClassUnknown
+	}
+
+	msg := strings.ToLower(err.Error())
+
+	// JSON-RPC 错误对象：只有 revert、参数错误、方法不存在等确定性错误不重试；
+	// 服务商内部错误、上游超时同样以错误对象返回，需要换节点
+	var rpcErr ethrpc.Error
+	if errors.As(err, &rpcErr) {
+		code := rpcErr.ErrorCode()
+		switch {
+		case code == -32005 || code == http.StatusTooManyRequests || containsAny(msg, rateLimitMessages):
+			return ClassRateLimited
+		case containsAny(msg, staleMessages):
+			return ClassStale
+		case code == codeExecutionReverted || code == codeInvalidParams || code == codeMethodNotFound || containsAny(msg, executionMessages):
+			return ClassExecution
+		case containsAny(msg, timeoutMessages):
+			return ClassTimeout
+		case code == codeInternalError || containsAny(msg, providerFaultMessages):
+			return ClassTransport
+		}
+		return ClassUnknown
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ClassTimeout
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ClassTransport
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return ClassTransport
+	}
+
+	switch {
+	case containsAny(msg, rateLimitMessages):
+		return ClassRateLimited
+	case containsAny(msg, staleMessages):
+		return ClassStale
+	case containsAny(msg, transportMessages):
+		return ClassTransport
+	case containsAny(msg, timeoutMessages):
+		return ClassTimeout
+	}
+	return ClassUnknown
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
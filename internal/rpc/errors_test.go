@@ -0,0 +1,51 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+)
+
+// jsonrpcError 模拟节点返回的 JSON-RPC 错误对象
+type jsonrpcError struct {
+	code int
+	msg  string
+}
+
+func (e jsonrpcError) Error() string  { return e.msg }
+func (e jsonrpcError) ErrorCode() int { return e.code }
+
+var _ ethrpc.Error = jsonrpcError{}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorClass
+	}{
+		{jsonrpcError{3, "execution reverted"}, ClassExecution},
+		{jsonrpcError{-32000, "execution reverted: Ownable: caller is not the owner"}, ClassExecution},
+		{jsonrpcError{-32602, "invalid argument 0: hex string without 0x prefix"}, ClassExecution},
+		{jsonrpcError{-32601, "the method debug_traceBlock does not exist/is not available"}, ClassExecution},
+		{jsonrpcError{-32603, "internal error"}, ClassTransport},
+		{jsonrpcError{-32000, "upstream connect error or disconnect/reset before headers"}, ClassTransport},
+		{jsonrpcError{-32000, "request timed out"}, ClassTimeout},
+		{jsonrpcError{-32000, "execution timeout"}, ClassTimeout},
+		{jsonrpcError{-32005, "limit exceeded"}, ClassRateLimited},
+		{jsonrpcError{-32000, "header not found"}, ClassStale},
+		{jsonrpcError{-32000, "something unexpected"}, ClassUnknown},
+		{ethrpc.HTTPError{StatusCode: 429}, ClassRateLimited},
+		{ethrpc.HTTPError{StatusCode: 502}, ClassTransport},
+		{fmt.Errorf("eth_getLogs: %w", context.DeadlineExceeded), ClassTimeout},
+		{context.Canceled, ClassCanceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.want {
+				t.Errorf("Classify(%v) = %s, want %s", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/chaintest"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// newSimManager 为一组模拟节点创建管理器，第 i 个节点名为 node<i>、优先级为 i
+func newSimManager(t *testing.T, opts Options, nodes ...*chaintest.Node) *Manager {
+	t.Helper()
+
+	configs := make([]NodeConfig, len(nodes))
+	for i, node := range nodes {
+		configs[i] = NodeConfig{Name: fmt.Sprintf("node%d", i), URL: node.URL(), Priority: i, Timeout: 200 * time.Millisecond}
+	}
+	m, err := NewManager(configs, opts, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Stop)
+	return m
+}
+
+// blockNumber 与引擎一样为每次尝试单独设置超时，经由管理器查询链头
+func blockNumber(m *Manager) (uint64, error) {
+	var n uint64
+	err := m.ExecuteContext(context.Background(), func(ctx context.Context, node *Node) error {
+		ctx, cancel := context.WithTimeout(ctx, node.Config.Timeout)
+		defer cancel()
+
+		var err error
+		n, err = node.Client.BlockNumber(ctx)
+		return err
+	})
+	return n, err
+}
+
+func TestFailover(t *testing.T) {
+	tests := []struct {
+		name  string
+		fault chaintest.Fault
+	}{
+		{"rate limited", chaintest.FaultRateLimit},
+		{"timeout", chaintest.FaultTimeout},
+		{"server error", chaintest.FaultServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := chaintest.NewChain()
+			chain.MineEmpty(20)
+
+			primary, backup := chaintest.NewNode(t, chain), chaintest.NewNode(t, chain)
+			m := newSimManager(t, Options{}, primary, backup)
+			primary.SetFault(tt.fault)
+
+			n, err := blockNumber(m)
+			if err != nil {
+				t.Fatalf("call failed despite a healthy backup: %v", err)
+			}
+			if n != 20 {
+				t.Errorf("block number = %d, want 20", n)
+			}
+			if primary.Requests() != 1 {
+				t.Errorf("primary received %d requests, want 1", primary.Requests())
+			}
+			if backup.Calls("eth_blockNumber") != 1 {
+				t.Errorf("backup served %d calls, want 1", backup.Calls("eth_blockNumber"))
+			}
+		})
+	}
+}
+
+func TestRateLimitedNodeSkippedUntilRetryAfter(t *testing.T) {
+	chain := chaintest.NewChain()
+	chain.MineEmpty(5)
+
+	primary, backup := chaintest.NewNode(t, chain), chaintest.NewNode(t, chain)
+	m := newSimManager(t, Options{}, primary, backup)
+	primary.SetFault(chaintest.FaultRateLimit)
+
+	for range 3 {
+		if _, err := blockNumber(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 429 带 Retry-After: 1，之后一秒内的请求不应再打到主节点
+	if primary.Requests() != 1 {
+		t.Errorf("rate limited primary received %d requests, want 1", primary.Requests())
+	}
+	if backup.Calls("eth_blockNumber") != 3 {
+		t.Errorf("backup served %d calls, want 3", backup.Calls("eth_blockNumber"))
+	}
+}
+
+func TestAllNodesDown(t *testing.T) {
+	chain := chaintest.NewChain()
+	primary, backup := chaintest.NewNode(t, chain), chaintest.NewNode(t, chain)
+	m := newSimManager(t, Options{}, primary, backup)
+	primary.SetFault(chaintest.FaultServerError)
+	backup.SetFault(chaintest.FaultServerError)
+
+	if _, err := blockNumber(m); err == nil {
+		t.Fatal("call succeeded with every node down")
+	}
+}
+
+func TestLaggingNodeMarkedUnhealthy(t *testing.T) {
+	chain := chaintest.NewChain()
+	chain.MineEmpty(100)
+
+	primary, backup := chaintest.NewNode(t, chain), chaintest.NewNode(t, chain)
+	m := newSimManager(t, Options{}, primary, backup)
+
+	primary.SetLag(defaultMaxBlockLag + 5)
+	m.checkAllNodes()
+
+	healthy := m.GetHealthyNodes()
+	if len(healthy) != 1 || healthy[0].Config.Name != "node1" {
+		t.Fatalf("healthy nodes = %v, want only node1", nodeNames(healthy))
+	}
+	if n, err := blockNumber(m); err != nil || n != 100 {
+		t.Fatalf("blockNumber() = %d, %v; want 100 from the up-to-date node", n, err)
+	}
+
+	// 追上后下一轮健康检查恢复
+	primary.SetLag(0)
+	m.checkAllNodes()
+	if healthy := m.GetHealthyNodes(); len(healthy) != 2 {
+		t.Errorf("healthy nodes after catching up = %v, want both", nodeNames(healthy))
+	}
+}
+
+func TestQuorumQuarantinesForkedNode(t *testing.T) {
+	chain := chaintest.NewChain()
+	chain.MineEmpty(30)
+
+	// node2 停留在另一条分叉上：最近 3 个区块与其余节点不同
+	fork := chain.Clone()
+	fork.Fork(3)
+	fork.MineEmpty(3)
+
+	nodes := []*chaintest.Node{chaintest.NewNode(t, chain), chaintest.NewNode(t, chain), chaintest.NewNode(t, fork)}
+	m := newSimManager(t, Options{}, nodes...)
+
+	header, err := Quorum(context.Background(), m, 2, func(ctx context.Context, node *Node) (*types.Header, error) {
+		return node.Client.HeaderByNumber(ctx, chain.Header(29).Number)
+	}, func(h *types.Header) string { return h.Hash().Hex() })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Hash() != chain.Header(29).Hash() {
+		t.Errorf("quorum header = %s, want canonical %s", header.Hash(), chain.Header(29).Hash())
+	}
+
+	forked, err := m.Node("node2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !forked.StatusSnapshot().QuarantinedUntil.After(time.Now()) {
+		t.Error("node on the minority fork was not quarantined")
+	}
+	for _, node := range m.GetHealthyNodes() {
+		if node == forked {
+			t.Error("quarantined node still selectable")
+		}
+	}
+}
+
+func TestFailingHealthChecksOpenBreaker(t *testing.T) {
+	chain := chaintest.NewChain()
+	chain.MineEmpty(10)
+
+	primary, backup := chaintest.NewNode(t, chain), chaintest.NewNode(t, chain)
+	m := newSimManager(t, Options{Breaker: BreakerConfig{Cooldown: 50 * time.Millisecond}}, primary, backup)
+	primary.SetFault(chaintest.FaultServerError)
+
+	for range 3 {
+		m.checkAllNodes()
+	}
+
+	node, _ := m.Node("node0")
+	status := node.StatusSnapshot()
+	if status.Circuit != CircuitOpen {
+		t.Fatalf("circuit after failing health checks = %s, want open", status.Circuit)
+	}
+	if !status.IsHealthy {
+		t.Error("failing health checks marked the node unhealthy; only lag should")
+	}
+	if healthy := m.GetHealthyNodes(); len(healthy) != 1 || healthy[0].Config.Name != "node1" {
+		t.Fatalf("healthy nodes = %v, want only node1 while node0's breaker is open", nodeNames(healthy))
+	}
+
+	// 冷却期结束后由半开探测恢复，不必等下一轮健康检查
+	primary.SetFault(chaintest.FaultNone)
+	time.Sleep(60 * time.Millisecond)
+	backup.SetFault(chaintest.FaultServerError)
+	if _, err := blockNumber(m); err != nil {
+		t.Fatal(err)
+	}
+	if circuit := node.StatusSnapshot().Circuit; circuit != CircuitClosed {
+		t.Errorf("circuit after successful probe = %s, want closed", circuit)
+	}
+}
+
+func TestBestHeadIgnoresRunawayNode(t *testing.T) {
+	chain := chaintest.NewChain()
+	chain.MineEmpty(100)
+	// 连错网络或返回错误高度的节点，报告的链头远高于其它节点
+	runaway := chaintest.NewChain()
+	runaway.MineEmpty(200)
+
+	m := newSimManager(t, Options{}, chaintest.NewNode(t, chain), chaintest.NewNode(t, chain), chaintest.NewNode(t, runaway))
+	m.checkAllNodes()
+
+	if best := m.BestHead(); best != 100 {
+		t.Fatalf("best head = %d, want 100 (median of reported heads)", best)
+	}
+	for _, name := range []string{"node0", "node1"} {
+		node, _ := m.Node(name)
+		if status := node.StatusSnapshot(); !status.IsHealthy {
+			t.Errorf("%s marked unhealthy by a runaway node: %v", name, status.LastError)
+		}
+	}
+
+	// 调用方经由失控节点拿到的链头同样不能抬高共识链头
+	bad, _ := m.Node("node2")
+	if err := m.RecordHead(bad, 200); err != nil {
+		t.Fatal(err)
+	}
+	if best := m.BestHead(); best != 100 {
+		t.Errorf("best head after RecordHead = %d, want 100", best)
+	}
+}
+
+func TestRemovedNodeHeightDropped(t *testing.T) {
+	chain := chaintest.NewChain()
+	chain.MineEmpty(100)
+	runaway := chaintest.NewChain()
+	runaway.MineEmpty(200)
+
+	// 只有两个节点时取最大值，失控节点会让正常节点被判为落后
+	m := newSimManager(t, Options{}, chaintest.NewNode(t, chain), chaintest.NewNode(t, runaway))
+	m.checkAllNodes()
+	if best := m.BestHead(); best != 200 {
+		t.Fatalf("best head = %d, want 200", best)
+	}
+
+	if err := m.RemoveNode(context.Background(), "node1"); err != nil {
+		t.Fatal(err)
+	}
+	if best := m.BestHead(); best != 100 {
+		t.Fatalf("best head after removing the runaway node = %d, want 100", best)
+	}
+
+	m.checkAllNodes()
+	if healthy := m.GetHealthyNodes(); len(healthy) != 1 || healthy[0].Config.Name != "node0" {
+		t.Errorf("healthy nodes = %v, want node0 back online", nodeNames(healthy))
+	}
+}
+
+func nodeNames(nodes []*Node) []string {
+	names := make([]string, len(nodes))
+	for i, node := range nodes {
+		names[i] = node.Config.Name
+	}
+	return names
+}
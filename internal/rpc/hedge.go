@@ -0,0 +1,204 @@
+package rpc
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// HedgeConfig 对冲请求参数，零值字段使用默认值
+type HedgeConfig struct {
+	// Enabled 是否启用对冲；关闭时 Hedged 等价于 ExecuteContext
+	Enabled bool
+	// Percentile 首个请求超过该分位的历史耗时仍未返回时，向第二个节点发出相同请求
+	Percentile float64
+	// MinDelay / MaxDelay 对冲等待时间的下限与上限
+	MinDelay time.Duration
+	MaxDelay time.Duration
+}
+
+func (c HedgeConfig) withDefaults() HedgeConfig {
+	if c.Percentile <= 0 || c.Percentile >= 1 {
+		c.Percentile = 0.95
+	}
+	if c.MinDelay <= 0 {
+		c.MinDelay = 50 * time.Millisecond
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = 2 * time.Second
+	}
+	return c
+}
+
+// latencyWindowSize 每类操作保留最近多少次成功请求的耗时用于计算分位数
+const latencyWindowSize = 256
+
+// minHedgeSamples 样本不足时使用 MaxDelay，避免冷启动阶段过早对冲
+const minHedgeSamples = 20
+
+// latencyTracker 按操作名记录最近的成功请求耗时
+type latencyTracker struct {
+	mu      sync.Mutex
+	windows map[string]*latencyWindow
+}
+
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+}
+
+func newLatencyTracker() *latencyTracker {
+	return &latencyTracker{windows: make(map[string]*latencyWindow)}
+}
+
+func (t *latencyTracker) observe(op string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	w, ok := t.windows[op]
+	if !ok {
+		w = &latencyWindow{}
+		t.windows[op] = w
+	}
+	if len(w.samples) < latencyWindowSize {
+		w.samples = append(w.samples, d)
+		return
+	}
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % latencyWindowSize
+}
+
+// percentile 返回 op 的第 p 分位耗时，样本不足时 ok 为 false
+func (t *latencyTracker) percentile(op string, p float64) (time.Duration, bool) {
+	t.mu.Lock()
+	w, found := t.windows[op]
+	var samples []time.Duration
+	if found {
+		samples = slices.Clone(w.samples)
+	}
+	t.mu.Unlock()
+
+	if len(samples) < minHedgeSamples {
+		return 0, false
+	}
+	slices.Sort(samples)
+	return samples[int(p*float64(len(samples)-1))], true
+}
+
+// hedgeDelay 计算 op 的对冲等待时间
+func (m *Manager) hedgeDelay(op string) time.Duration {
+	cfg := m.options.Hedge
+	d, ok := m.latencies.percentile(op, cfg.Percentile)
+	if !ok {
+		return cfg.MaxDelay
+	}
+	return min(max(d, cfg.MinDelay), cfg.MaxDelay)
+}
+
+// hedgeResult 一路对冲请求的结果
+type hedgeResult[T any] struct {
+	value T
+	class ErrorClass
+	err   error
+}
+
+// Hedged 对延迟敏感的调用发起对冲请求：先向选中的节点发出请求，
+// 若超过 op 历史耗时的分位数仍未返回，再向另一个健康节点发出同样的请求，取先成功的结果并取消另一路。
+// 两路都失败（或只有一个可用节点、未启用对冲）时退回常规重试，重试优先避开对冲中已经失败的节点。
+// op 用于区分不同操作的耗时分布，如 "eth_blockNumber"、"eth_getLogs"
+func Hedged[T any](ctx context.Context, m *Manager, op string, fn func(context.Context, *Node) (T, error)) (T, error) {
+	var zero T
+
+	var tried map[*Node]bool
+	if m.options.Hedge.Enabled {
+		value, failed, done, err := hedge(ctx, m, op, fn)
+		if done {
+			return value, err
+		}
+		tried = failed
+	}
+
+	req := requirementsFrom(ctx)
+	var value T
+	err := m.execute(ctx, tried, func(attempt int, tried map[*Node]bool) (*Node, error) {
+		return m.selectNode(req, tried)
+	}, func(ctx context.Context, node *Node) error {
+		start := time.Now()
+		v, err := fn(ctx, node)
+		if err != nil {
+			return err
+		}
+		m.latencies.observe(op, time.Since(start))
+		value = v
+		return nil
+	})
+	if err != nil {
+		return zero, err
+	}
+	return value, nil
+}
+
+// hedge 执行一轮对冲；done 为 false 表示应退回常规重试，tried 为本轮已经请求过的节点
+func hedge[T any](ctx context.Context, m *Manager, op string, fn func(context.Context, *Node) (T, error)) (value T, tried map[*Node]bool, done bool, err error) {
+	req := requirementsFrom(ctx)
+	primary, err := m.selectNode(req, nil)
+	if err != nil {
+		return value, nil, false, nil
+	}
+	tried = map[*Node]bool{primary: true}
+
+	raceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgeResult[T], 2)
+	launch := func(node *Node) {
+		go func() {
+			start := time.Now()
+			var v T
+			class, err := m.attempt(raceCtx, node, func(ctx context.Context, node *Node) error {
+				var err error
+				v, err = fn(ctx, node)
+				return err
+			})
+			if err == nil {
+				m.latencies.observe(op, time.Since(start))
+			}
+			results <- hedgeResult[T]{value: v, class: class, err: err}
+		}()
+	}
+
+	launch(primary)
+	inFlight := 1
+
+	timer := time.NewTimer(m.hedgeDelay(op))
+	defer timer.Stop()
+
+	var lastErr error
+	for inFlight > 0 {
+		select {
+		case res := <-results:
+			inFlight--
+			if res.err == nil {
+				return res.value, tried, true, nil
+			}
+			// 确定性错误换节点也一样，不必再等另一路或重试
+			if res.class == ClassExecution {
+				return value, tried, true, res.err
+			}
+			lastErr = res.err
+		case <-timer.C:
+			if secondary, err := m.selectNode(req, tried); err == nil && !tried[secondary] {
+				m.logger.Debug("hedging rpc request", "op", op, "primary", primary.Config.Name, "secondary", secondary.Config.Name)
+				tried[secondary] = true
+				launch(secondary)
+				inFlight++
+			}
+		case <-ctx.Done():
+			return value, tried, true, ctx.Err()
+		}
+	}
+
+	m.logger.Debug("hedged request failed on all nodes, falling back to retries", "op", op, "error", lastErr)
+	return value, tried, false, nil
+}
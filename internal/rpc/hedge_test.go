@@ -0,0 +1,147 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/chaintest"
+)
+
+// hedgedBlockNumber 经由 Hedged 查询链头；不设单次超时，挂起的节点只能靠对冲取消
+func hedgedBlockNumber(m *Manager) (uint64, error) {
+	return Hedged(context.Background(), m, "eth_blockNumber", func(ctx context.Context, node *Node) (uint64, error) {
+		return node.Client.BlockNumber(ctx)
+	})
+}
+
+func TestHedgeDelay(t *testing.T) {
+	m := newSimManager(t, Options{Hedge: HedgeConfig{Enabled: true, Percentile: 0.9, MinDelay: 5 * time.Millisecond, MaxDelay: 500 * time.Millisecond}},
+		chaintest.NewNode(t, chaintest.NewChain()))
+
+	// 样本不足时使用上限，避免冷启动阶段过早对冲
+	if d := m.hedgeDelay("op"); d != 500*time.Millisecond {
+		t.Errorf("delay without samples = %v, want MaxDelay", d)
+	}
+
+	for i := 1; i <= 100; i++ {
+		m.latencies.observe("op", time.Duration(i)*time.Millisecond)
+	}
+	if d := m.hedgeDelay("op"); d != 90*time.Millisecond {
+		t.Errorf("p90 delay = %v, want 90ms", d)
+	}
+
+	for range latencyWindowSize {
+		m.latencies.observe("fast", time.Millisecond)
+		m.latencies.observe("slow", 10*time.Second)
+	}
+	if d := m.hedgeDelay("fast"); d != 5*time.Millisecond {
+		t.Errorf("delay for fast op = %v, want MinDelay", d)
+	}
+	if d := m.hedgeDelay("slow"); d != 500*time.Millisecond {
+		t.Errorf("delay for slow op = %v, want MaxDelay", d)
+	}
+
+	// 窗口满后新样本覆盖最旧的样本
+	for range latencyWindowSize {
+		m.latencies.observe("op", 200*time.Millisecond)
+	}
+	if d := m.hedgeDelay("op"); d != 200*time.Millisecond {
+		t.Errorf("delay after the window rolled over = %v, want 200ms", d)
+	}
+}
+
+func TestHedgedSecondaryWins(t *testing.T) {
+	chain := chaintest.NewChain()
+	chain.MineEmpty(10)
+
+	primary, backup := chaintest.NewNode(t, chain), chaintest.NewNode(t, chain)
+	m := newSimManager(t, Options{Hedge: HedgeConfig{Enabled: true, MaxDelay: 20 * time.Millisecond}}, primary, backup)
+	primary.SetFault(chaintest.FaultTimeout)
+
+	start := time.Now()
+	n, err := hedgedBlockNumber(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 10 {
+		t.Errorf("block number = %d, want 10", n)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("hedged call took %v, the hung primary was not hedged", elapsed)
+	}
+	if backup.Calls("eth_blockNumber") != 1 {
+		t.Errorf("backup served %d calls, want 1", backup.Calls("eth_blockNumber"))
+	}
+
+	// 胜出后取消挂起的主节点请求
+	node, _ := m.Node("node0")
+	deadline := time.Now().Add(time.Second)
+	for node.inflight.Load() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("losing request on the primary was not cancelled")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHedgedPrimaryWithinDelay(t *testing.T) {
+	chain := chaintest.NewChain()
+	chain.MineEmpty(10)
+
+	primary, backup := chaintest.NewNode(t, chain), chaintest.NewNode(t, chain)
+	m := newSimManager(t, Options{Hedge: HedgeConfig{Enabled: true, MaxDelay: time.Second}}, primary, backup)
+
+	if _, err := hedgedBlockNumber(m); err != nil {
+		t.Fatal(err)
+	}
+	if primary.Calls("eth_blockNumber") != 1 || backup.Requests() != 0 {
+		t.Errorf("primary/backup calls = %d/%d, want 1/0: fast primary should not be hedged",
+			primary.Calls("eth_blockNumber"), backup.Requests())
+	}
+	if _, ok := m.latencies.percentile("eth_blockNumber", 0.5); ok {
+		t.Error("a single sample should not be enough to compute a percentile")
+	}
+}
+
+func TestHedgedFallbackSkipsFailedPrimary(t *testing.T) {
+	chain := chaintest.NewChain()
+	chain.MineEmpty(10)
+
+	primary, backup := chaintest.NewNode(t, chain), chaintest.NewNode(t, chain)
+	m := newSimManager(t, Options{Hedge: HedgeConfig{Enabled: true, MaxDelay: time.Second}}, primary, backup)
+	primary.SetFault(chaintest.FaultServerError)
+
+	// 主节点在对冲计时器触发前就失败，退回重试时应直接换到备用节点
+	start := time.Now()
+	n, err := hedgedBlockNumber(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 10 {
+		t.Errorf("block number = %d, want 10", n)
+	}
+	if primary.Requests() != 1 {
+		t.Errorf("failed primary received %d requests, want 1", primary.Requests())
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("fallback took %v, want it to skip the retry backoff on the failed primary", elapsed)
+	}
+}
+
+func TestHedgedExecutionErrorNotRetried(t *testing.T) {
+	chain := chaintest.NewChain()
+	primary, backup := chaintest.NewNode(t, chain), chaintest.NewNode(t, chain)
+	m := newSimManager(t, Options{Hedge: HedgeConfig{Enabled: true, MaxDelay: time.Second}}, primary, backup)
+
+	_, err := Hedged(context.Background(), m, "debug_unknown", func(ctx context.Context, node *Node) (any, error) {
+		var out any
+		return out, node.RPCClient.CallContext(ctx, &out, "debug_unknown")
+	})
+	if err == nil || Classify(err) != ClassExecution {
+		t.Fatalf("err = %v, want an execution error", err)
+	}
+	if backup.Requests() != 0 {
+		t.Errorf("deterministic error was retried on the backup (%d requests)", backup.Requests())
+	}
+}
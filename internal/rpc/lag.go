@@ -0,0 +1,94 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ErrNodeLagging 节点的区块高度落后已知最高区块超过阈值
+var ErrNodeLagging = errors.New("node is lagging behind the best known head")
+
+// defaultMaxBlockLag 默认允许的最大落后区块数；健康检查每 30 秒一轮，节点之间 2~3 个块的差距属于正常抖动
+const defaultMaxBlockLag uint64 = 10
+
+// refreshBestHead 按已注册、未被隔离节点最近报告的高度重新计算链头并返回
+// 3 个及以上节点时取中位数，单个报告过高的节点（连错网络、服务商偶发返回错误高度）无法抬高链头；
+// 节点更少时取最大值。高度不做单调累积，节点被摘除后它的高度随即不再计入
+func (m *Manager) refreshBestHead() uint64 {
+	m.mu.RLock()
+	nodes := m.nodes
+	m.mu.RUnlock()
+
+	now := time.Now()
+	heights := make([]uint64, 0, len(nodes))
+	for _, node := range nodes {
+		node.mu.RLock()
+		if node.Status.LatestBlock > 0 && !node.Status.QuarantinedUntil.After(now) {
+			heights = append(heights, node.Status.LatestBlock)
+		}
+		node.mu.RUnlock()
+	}
+
+	best := consensusHead(heights)
+	m.bestHead.Store(best)
+	return best
+}
+
+// consensusHead 从各节点报告的高度中选出可信的链头
+func consensusHead(heights []uint64) uint64 {
+	if len(heights) == 0 {
+		return 0
+	}
+	slices.Sort(heights)
+	if len(heights) >= 3 {
+		return heights[len(heights)/2]
+	}
+	return heights[len(heights)-1]
+}
+
+// BestHead 返回各节点共同认可的链头高度，见 refreshBestHead
+func (m *Manager) BestHead() uint64 {
+	return m.bestHead.Load()
+}
+
+// RecordHead 记录调用方从某个节点拿到的链头高度，并与已知最高区块比较；
+// 落后超过阈值时立即将节点标记为不健康并返回 ErrNodeLagging，调用方应换一个节点重试
+func (m *Manager) RecordHead(node *Node, height uint64) error {
+	node.mu.Lock()
+	node.Status.LatestBlock = height
+	node.mu.Unlock()
+
+	best := m.refreshBestHead()
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	return m.checkLagLocked(node, height, best)
+}
+
+// checkLagLocked 根据落后区块数更新节点状态，调用方需持有 node.mu 写锁
+// 高于链头的节点不算落后；它是否可信由 Quorum 等机制判断
+func (m *Manager) checkLagLocked(node *Node, height, best uint64) error {
+	var behind uint64
+	if best > height {
+		behind = best - height
+	}
+	node.Status.BlocksBehind = behind
+
+	if behind <= m.options.MaxBlockLag {
+		return nil
+	}
+
+	err := fmt.Errorf("%w: %d blocks behind (node %d, best %d)", ErrNodeLagging, behind, height, best)
+	node.Status.LastError = err
+	if node.Status.IsHealthy {
+		node.Status.IsHealthy = false
+		m.logger.Warn("node is lagging behind, marked as unhealthy",
+			"name", node.Config.Name,
+			"block", height,
+			"best_head", best,
+			"blocks_behind", behind)
+	}
+	return err
+}
@@ -5,34 +5,66 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math/big"
+	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	ethrpc "github.com/ethereum/go-ethereum/rpc"
 )
 
+// JWTSecretLength JWT 密钥的字节数，与 geth 的 jwtsecret 文件相同
+const JWTSecretLength = 32
+
 // ErrNoHealthyNodes 统一定义包级错误
 var ErrNoHealthyNodes = errors.New("no healthy nodes available")
 
+// ErrNoSubscriptionNodes 没有可用的 ws/wss 健康节点，调用方应退回轮询
+var ErrNoSubscriptionNodes = errors.New("no healthy websocket nodes available")
+
 // NodeConfig 节点配置
 type NodeConfig struct {
 	Name     string
-	URL      string
-	Priority int
-	Weight   int
+	URL      string // 支持 http(s):// 与 ws(s)://，后者可用于 eth_subscribe 订阅
+	Priority int    // 越小越优先
+	Weight   int    // 加权轮询策略下的权重，为 0 时按 1 处理
 	Timeout  time.Duration
+
+	// 客户端限流，按服务商套餐配置；均为 0 时不限制
+	RateLimit  float64 // 每秒请求数
+	Burst      int     // 令牌桶容量，为 0 时取 RateLimit
+	DailyLimit int64   // 每个 UTC 自然日的请求上限
+
+	Capabilities Capabilities
+
+	// Headers 每个请求附带的 HTTP 头（ws 节点在握手时发送），如服务商要求的 API Key 或 Authorization 头
+	Headers map[string]string
+	// JWTSecret 非空时每个请求附带 HS256 签名的 JWT（Authorization: Bearer），与 Headers 中的 Authorization 互斥；
+	// 长度必须为 JWTSecretLength
+	JWTSecret []byte
 }
 
 // NodeStatus 节点状态
 type NodeStatus struct {
-	IsHealthy     bool
+	IsHealthy     bool // 高度是否跟上链头；请求与探测失败由熔断器（Circuit）处理
 	LastCheckTime time.Time
 	LatestBlock   uint64
-	ResponseTime  time.Duration
-	ErrorCount    int
+	BlocksBehind  uint64        // 相对共识链头（Manager.BestHead）的落后数
+	ResponseTime  time.Duration // 成功请求耗时的 EWMA
+	ErrorCount    int           // 连续失败次数，任意一次成功后清零
+	Circuit       string        // 熔断器状态：closed/open/half-open
 	SuccessCount  int
 	LastError     error
+	// 因 Quorum 结果与多数节点不一致被隔离到的时间，零值或已过去表示未隔离
+	QuarantinedUntil time.Time
+
+	DailyRequests  int64   // 当天（UTC）已发出的请求数
+	DailyRemaining int64   // 当天剩余额度，-1 表示不限
+	RateTokens     float64 // 令牌桶当前可用令牌数，-1 表示不限速（仅 StatusSnapshot 中有效）
 }
 
 // Node 节点实例
@@ -42,6 +74,18 @@ type Node struct {
 	Client    *ethclient.Client
 	RPCClient *ethrpc.Client
 	mu        sync.RWMutex
+
+	breaker    *breaker     // 受 mu 保护
+	budget     *budget      // 受 mu 保护（limiter 自带锁）
+	retryAfter atomic.Int64 // 服务商要求的最早重试时间（UnixNano），0 表示没有限制
+	inflight   atomic.Int64 // 正在执行的请求数，摘除节点时等待其归零
+}
+
+// ConfigSnapshot 返回节点配置的副本；Priority 与 Weight 可在运行时修改，读取时需要加锁
+func (n *Node) ConfigSnapshot() NodeConfig {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.Config
 }
 
 // Manager 节点管理器
@@ -50,6 +94,25 @@ type Manager struct {
 	mu                  sync.RWMutex
 	healthCheckInterval time.Duration
 	maxRetries          int
+	options             Options
+
+	// 各节点共同认可的链头（见 refreshBestHead），用于识别落后节点
+	bestHead atomic.Uint64
+
+	// 现有节点所在链的 ID，首次新增节点时查询并缓存（见 chainID）
+	chainIDMu    sync.Mutex
+	knownChainID *big.Int
+
+	// 各类操作最近的耗时分布，用于计算对冲等待时间
+	latencies *latencyTracker
+
+	// HTTP 节点共用的底层传输；录制模式下为包装后的 recorder
+	transport http.RoundTripper
+	recorder  *Recorder
+
+	// 加权轮询的当前权重
+	weightMu       sync.Mutex
+	currentWeights map[*Node]int
 
 	//依赖注入
 	logger *slog.Logger
@@ -61,10 +124,26 @@ type Manager struct {
 }
 
 // NewManager 创建节点管理器，强制要求传入 logger
-func NewManager(configs []NodeConfig, logger *slog.Logger) (*Manager, error) {
+func NewManager(configs []NodeConfig, opts Options, logger *slog.Logger) (*Manager, error) {
 	if len(configs) == 0 {
 		return nil, errors.New("at least one node configuration is required")
 	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if len(configs) < opts.MinNodes {
+		return nil, fmt.Errorf("%w: %d nodes configured, need at least %d", ErrBelowMinNodes, len(configs), opts.MinNodes)
+	}
+	if opts.Strategy == "" {
+		opts.Strategy = StrategyPriority
+	}
+	if opts.MaxBlockLag == 0 {
+		opts.MaxBlockLag = defaultMaxBlockLag
+	}
+	opts.Hedge = opts.Hedge.withDefaults()
+	if opts.Quarantine <= 0 {
+		opts.Quarantine = defaultQuarantine
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -72,11 +151,24 @@ func NewManager(configs []NodeConfig, logger *slog.Logger) (*Manager, error) {
 		nodes:               make([]*Node, 0, len(configs)),
 		healthCheckInterval: 30 * time.Second,
 		maxRetries:          3,
+		options:             opts,
+		currentWeights:      make(map[*Node]int),
+		latencies:           newLatencyTracker(),
+		transport:           opts.Transport,
 		logger:              logger,
 		ctx:                 ctx,
 		cancel:              cancel,
 	}
 
+	if m.transport == nil {
+		m.transport = http.DefaultTransport
+	}
+	if opts.RecordFile != "" {
+		m.recorder = NewRecorder(m.transport)
+		m.transport = m.recorder
+		m.logger.Info("recording rpc traffic", "file", opts.RecordFile)
+	}
+
 	for _, config := range configs {
 		node, err := m.createNode(config)
 		if err != nil {
@@ -114,88 +206,274 @@ func (m *Manager) createNode(config NodeConfig) (*Node, error) {
 	if config.Timeout == 0 {
 		config.Timeout = 10 * time.Second
 	}
+	if config.Weight <= 0 {
+		config.Weight = 1
+	}
+	if isWebSocketURL(config.URL) {
+		config.Capabilities.WebSocket = true
+	}
 
-	rpcClient, err := ethrpc.DialContext(m.ctx, config.URL)
+	node := &Node{
+		Config: config,
+		Status: NodeStatus{
+			IsHealthy:      true,
+			LastCheckTime:  time.Now(),
+			Circuit:        CircuitClosed,
+			DailyRemaining: -1,
+		},
+		breaker: newBreaker(m.options.Breaker),
+		budget:  newBudget(config),
+	}
+	if config.DailyLimit > 0 {
+		node.Status.DailyRemaining = config.DailyLimit
+	}
+
+	// HTTP 节点经由 retryAfterTransport 记录限流响应中的 Retry-After（ws 节点会忽略该选项）
+	httpClient := &http.Client{Transport: &retryAfterTransport{base: m.transport, node: node}}
+
+	options := []ethrpc.ClientOption{ethrpc.WithHTTPClient(httpClient)}
+	if len(config.Headers) > 0 {
+		headers := make(http.Header, len(config.Headers))
+		for key, value := range config.Headers {
+			headers.Set(key, value)
+		}
+		options = append(options, ethrpc.WithHeaders(headers))
+	}
+	if len(config.JWTSecret) > 0 {
+		if len(config.JWTSecret) != JWTSecretLength {
+			return nil, fmt.Errorf("jwt secret must be %d bytes, got %d", JWTSecretLength, len(config.JWTSecret))
+		}
+		options = append(options, ethrpc.WithHTTPAuth(jwtAuth(config.JWTSecret)))
+	}
+
+	rpcClient, err := ethrpc.DialOptions(m.ctx, config.URL, options...)
 	if err != nil {
 		return nil, fmt.Errorf("dial rpc failed: %w", err)
 	}
 
-	client := ethclient.NewClient(rpcClient)
-
-	return &Node{
-		Config:    config,
-		Client:    client,
-		RPCClient: rpcClient,
-		Status: NodeStatus{
-			IsHealthy:     true,
-			LastCheckTime: time.Now(),
-		},
-	}, nil
+	node.RPCClient = rpcClient
+	node.Client = ethclient.NewClient(rpcClient)
+	return node, nil
 }
 
-// GetHealthyNode 获取最优健康节点
+// GetHealthyNode 按选择策略获取一个健康节点
 func (m *Manager) GetHealthyNode() (*Node, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	var bestNode *Node
-
-	for _, node := range m.nodes {
-		node.mu.RLock()
-		isHealthy := node.Status.IsHealthy
-		priority := node.Config.Priority
-		node.mu.RUnlock()
+	return m.selectNode(Requirements{}, nil)
+}
 
-		if !isHealthy {
-			continue
-		}
+// GetHealthyNodes 返回全部健康节点，按选择策略排序（priority/weighted 按优先级，latency 按响应时间）
+func (m *Manager) GetHealthyNodes() []*Node {
+	return m.healthyNodes(Requirements{})
+}
 
-		if bestNode == nil || priority < bestNode.Config.Priority {
-			bestNode = node
-		}
-	}
+// healthyNodes 返回满足 req 的健康节点，排序规则与 GetHealthyNodes 相同
+func (m *Manager) healthyNodes(req Requirements) []*Node {
+	candidates := m.healthyCandidates(req)
+	m.sortCandidates(candidates)
 
-	if bestNode == nil {
-		return nil, ErrNoHealthyNodes
+	nodes := make([]*Node, len(candidates))
+	for i, c := range candidates {
+		nodes[i] = c.node
 	}
-	return bestNode, nil
+	return nodes
 }
 
 // ExecuteWithRetry 核心执行器：执行操作并自动重试
+// 不感知调用方的 context，新代码请使用 ExecuteContext
 func (m *Manager) ExecuteWithRetry(fn func(*ethclient.Client) error) error {
+	return m.ExecuteContext(context.Background(), func(_ context.Context, node *Node) error {
+		return fn(node.Client)
+	})
+}
+
+// ExecuteContext 按选择策略挑选节点执行 fn，失败时按错误类型决定是否换节点重试：
+//   - 调用方取消或确定性的 JSON-RPC 错误（revert 等）立即返回，不计入节点故障
+//   - 节点数据落后（header not found 等）换节点重试，不计入节点故障
+//   - 超时、连接错误、限流等传输层/服务商故障计入熔断器，指数退避（带抖动、遵守 Retry-After）后重试
+//
+// 重试优先选择本次调用尚未尝试过的节点；退避等待期间 ctx 取消会立即返回。
+// ctx 上通过 WithRequirements 声明的能力要求（如归档数据）会限制可选节点
+func (m *Manager) ExecuteContext(ctx context.Context, fn func(context.Context, *Node) error) error {
+	req := requirementsFrom(ctx)
+	return m.execute(ctx, nil, func(attempt int, tried map[*Node]bool) (*Node, error) {
+		return m.selectNode(req, tried)
+	}, fn)
+}
+
+// ExecuteContextAt 从第 slot 个健康节点（按策略排序后取模）开始执行，每次重试顺延到下一个节点。
+// 并发任务使用不同的 slot，即可把请求分摊到所有健康节点上；
+// weighted 策略下加权轮询本身就会分摊请求，直接按权重选择以遵守各家额度比例
+func (m *Manager) ExecuteContextAt(ctx context.Context, slot int, fn func(context.Context, *Node) error) error {
+	if m.options.Strategy == StrategyWeighted {
+		return m.ExecuteContext(ctx, fn)
+	}
+	req := requirementsFrom(ctx)
+	return m.execute(ctx, nil, func(attempt int, _ map[*Node]bool) (*Node, error) {
+		nodes := m.healthyNodes(req)
+		if len(nodes) == 0 {
+			return nil, ErrNoHealthyNodes
+		}
+		return nodes[(slot+attempt)%len(nodes)], nil
+	}, fn)
+}
+
+// execute 按 pick 选出的节点重试 fn；tried 为之前已经失败过的节点（可为 nil），pick 会优先避开它们
+func (m *Manager) execute(ctx context.Context, tried map[*Node]bool, pick func(attempt int, tried map[*Node]bool) (*Node, error), fn func(context.Context, *Node) error) error {
 	var lastErr error
+	var delay time.Duration
+	if tried == nil {
+		tried = make(map[*Node]bool)
+	}
 
 	for attempt := 0; attempt < m.maxRetries; attempt++ {
-		node, err := m.GetHealthyNode()
+		if attempt > 0 {
+			if err := sleepContext(ctx, delay); err != nil {
+				return err
+			}
+		}
+
+		node, err := pick(attempt, tried)
 		if err != nil {
 			lastErr = err
-			time.Sleep(time.Second * time.Duration(attempt+1))
+			delay = backoff(attempt)
 			continue
 		}
+		tried[node] = true
 
-		err = fn(node.Client)
+		class, err := m.attempt(ctx, node, fn)
 		if err == nil {
-			node.mu.Lock()
-			node.Status.SuccessCount++
-			node.mu.Unlock()
 			return nil
 		}
+		if !class.Retryable() {
+			return err
+		}
+
+		lastErr = err
+		if class == classSkipped {
+			// 请求没有发出，直接换下一个节点
+			delay = 0
+			continue
+		}
+		delay = backoff(attempt)
+
+		// 被限流且没有其它节点可用时，等到服务商允许的时间再重试
+		if class == ClassRateLimited {
+			if wait := node.retryAfterRemaining(time.Now()); wait > delay && len(m.healthyCandidates(requirementsFrom(ctx))) == 0 {
+				delay = wait
+			}
+		}
+
+		m.logger.Debug("rpc call failed, retrying", "name", node.Config.Name, "class", class, "attempt", attempt+1, "delay", delay, "error", err)
+	}
+	return fmt.Errorf("operation failed after %d retries: %w", m.maxRetries, lastErr)
+}
+
+// attempt 在指定节点上执行一次 fn：检查熔断器、占用额度、执行并记录结果
+// 熔断器拒绝或额度用完时请求不会发出，返回 classSkipped
+func (m *Manager) attempt(ctx context.Context, node *Node, fn func(context.Context, *Node) error) (ErrorClass, error) {
+	// 选中节点到发出请求之间，半开探测名额可能已被并发请求占用
+	node.mu.Lock()
+	allowed := node.breaker.allow(time.Now())
+	node.Status.Circuit = node.breaker.state
+	node.mu.Unlock()
+	if !allowed {
+		return classSkipped, fmt.Errorf("%s: %w", node.Config.Name, ErrCircuitOpen)
+	}
 
+	// 客户端限流：当天额度用完时换节点，令牌桶不足时排队等待
+	if err := node.acquireN(ctx, m, callsFrom(ctx)); err != nil {
 		node.mu.Lock()
+		node.breaker.release()
+		node.mu.Unlock()
+		if ctx.Err() != nil {
+			return ClassCanceled, ctx.Err()
+		}
+		return classSkipped, fmt.Errorf("%s: %w", node.Config.Name, err)
+	}
+
+	node.inflight.Add(1)
+	start := time.Now()
+	err := fn(ctx, node)
+	node.inflight.Add(-1)
+	if err == nil {
+		m.recordResult(node, nil, ClassUnknown, time.Since(start))
+		return ClassUnknown, nil
+	}
+
+	class := Classify(err)
+	if ctx.Err() != nil {
+		class = ClassCanceled
+	}
+	m.recordResult(node, err, class, time.Since(start))
+	return class, err
+}
+
+// recordResult 更新节点的请求统计与熔断器状态，只有 class.Penalizes() 的错误才计为节点故障
+func (m *Manager) recordResult(node *Node, err error, class ErrorClass, elapsed time.Duration) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	failed := false
+	switch {
+	case err == nil:
+		node.Status.SuccessCount++
+		node.Status.ErrorCount = 0
+		node.observeLatency(elapsed)
+	case class == ClassExecution:
+		// 节点正常给出了确定性的错误响应，说明节点本身是好的
+		node.Status.ErrorCount = 0
+	case class.Penalizes():
+		failed = true
 		node.Status.ErrorCount++
 		node.Status.LastError = err
+	default:
+		// 调用方取消、节点数据落后：不说明节点有故障，只归还半开探测名额
+		node.breaker.release()
+		return
+	}
+
+	if !node.breaker.record(failed, time.Now()) {
+		return
+	}
 
-		if node.Status.ErrorCount >= 3 {
-			node.Status.IsHealthy = false
-			m.logger.Warn("node marked as unhealthy due to repeated failures", "name", node.Config.Name, "error", err)
+	node.Status.Circuit = node.breaker.state
+	switch node.breaker.state {
+	case CircuitOpen:
+		m.logger.Warn("node circuit breaker opened",
+			"name", node.Config.Name,
+			"cooldown", node.breaker.config.Cooldown,
+			"error", err)
+	case CircuitClosed:
+		m.logger.Info("node circuit breaker closed after successful probe", "name", node.Config.Name)
+	}
+}
+
+// SupportsSubscriptions 节点连接是否支持 eth_subscribe（ws/wss/ipc）
+func (n *Node) SupportsSubscriptions() bool {
+	return n.RPCClient.SupportsSubscriptions()
+}
+
+// SubscribeNewHead 在排序最靠前的 ws/wss 健康节点上订阅 newHeads
+// 订阅断开后调用方会从 Subscription.Err() 收到错误，可再次调用以切换到其它节点
+func (m *Manager) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	var lastErr error = ErrNoSubscriptionNodes
+
+	for _, node := range m.GetHealthyNodes() {
+		if !node.SupportsSubscriptions() {
+			continue
 		}
 
-		node.mu.Unlock()
+		sub, err := node.Client.SubscribeNewHead(ctx, ch)
+		if err != nil {
+			m.logger.Warn("failed to subscribe to new heads", "name", node.Config.Name, "error", err)
+			lastErr = err
+			continue
+		}
 
-		lastErr = err
-		time.Sleep(time.Second * time.Duration(attempt+1))
+		m.logger.Info("subscribed to new heads", "name", node.Config.Name)
+		return sub, nil
 	}
-	return fmt.Errorf("operation failed after %d retries: %w", m.maxRetries, lastErr)
+	return nil, lastErr
 }
 
 func (m *Manager) startHealthCheck() {
@@ -212,61 +490,96 @@ func (m *Manager) startHealthCheck() {
 	}
 }
 
+// checkAllNodes 并发探测全部节点，探测结束后按本轮各节点的高度重新计算链头，再统一判断落后
+// 先探测后比较，结果与各节点探测完成的先后无关
 func (m *Manager) checkAllNodes() {
 	m.mu.RLock()
 	nodes := m.nodes
 	m.mu.RUnlock()
 
+	probed := make([]bool, len(nodes))
 	var wg sync.WaitGroup
-	for _, node := range nodes {
+	for i, node := range nodes {
 		wg.Add(1)
 		go func(n *Node) {
 			defer wg.Done()
-			m.checkNodeHealth(n)
+			probed[i] = m.probeNode(n)
 		}(node)
 	}
 	wg.Wait()
+
+	best := m.refreshBestHead()
+	for i, node := range nodes {
+		if probed[i] {
+			m.updateHealth(node, best)
+		}
+	}
 }
 
+// checkNodeHealth 立即检查单个节点，与其它节点上一次报告的高度比较
 func (m *Manager) checkNodeHealth(node *Node) {
+	if m.probeNode(node) {
+		m.updateHealth(node, m.refreshBestHead())
+	}
+}
+
+// probeNode 向节点请求 eth_blockNumber 并记录高度与延迟，返回探测是否成功
+func (m *Manager) probeNode(node *Node) bool {
 	ctx, cancel := context.WithTimeout(m.ctx, node.Config.Timeout)
 	defer cancel()
 
+	// 健康检查同样消耗服务商额度，计入当天请求数；额度已用完时跳过，避免把节点判成故障
+	if node.countRequests(time.Now(), 1) != nil {
+		return false
+	}
+
 	startTime := time.Now()
 	blockNumber, err := node.Client.BlockNumber(ctx)
 	responseTime := time.Since(startTime)
 
+	// 探测结果与普通请求一样交给熔断器：连续失败时熔断、冷却后半开探测恢复，
+	// 不再因为几次失败直接下线节点；管理器停止导致的取消不计入
+	if m.ctx.Err() == nil {
+		class := ClassUnknown
+		if err != nil {
+			class = Classify(err)
+		}
+		m.recordResult(node, err, class, responseTime)
+	}
+
 	node.mu.Lock()
 	defer node.mu.Unlock()
 
 	node.Status.LastCheckTime = time.Now()
-	node.Status.ResponseTime = responseTime
 
 	if err != nil {
-		node.Status.ErrorCount++
 		node.Status.LastError = err
-		if node.Status.ErrorCount >= 3 && node.Status.IsHealthy {
-			node.Status.IsHealthy = false
-			m.logger.Warn("health check failed, node offline",
-				"name", node.Config.Name,
-				"response_time", responseTime,
-				"error", err)
-		}
-		return
+		m.logger.Debug("health check failed", "name", node.Config.Name, "response_time", responseTime, "error", err)
+		return false
 	}
 
 	node.Status.LatestBlock = blockNumber
 	node.Status.LastError = nil
+	return true
+}
+
+// updateHealth 探测成功后根据落后区块数更新节点的健康状态
+// IsHealthy 只反映节点是否落后；请求与探测失败由熔断器处理
+func (m *Manager) updateHealth(node *Node, best uint64) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	// 节点能响应但高度落后太多，同样视为不健康，避免引擎从它读到过时的链头
+	if m.checkLagLocked(node, node.Status.LatestBlock, best) != nil {
+		return
+	}
 
 	if !node.Status.IsHealthy {
-		m.logger.Info("node recovered and is back online",
+		m.logger.Info("node caught up and is back online",
 			"name", node.Config.Name,
-			"block", blockNumber)
+			"block", node.Status.LatestBlock)
 	}
-
 	node.Status.IsHealthy = true
-	node.Status.ErrorCount = 0
-	node.Status.SuccessCount++
 }
 
 // Stop 完美契合 Let's Go Further 的优雅停机
@@ -282,5 +595,13 @@ func (m *Manager) Stop() {
 			node.Client.Close()
 		}
 	}
+
+	if m.recorder != nil {
+		if err := m.recorder.Save(m.options.RecordFile); err != nil {
+			m.logger.Error("failed to save rpc recording", "file", m.options.RecordFile, "error", err)
+		} else {
+			m.logger.Info("rpc recording saved", "file", m.options.RecordFile, "interactions", len(m.recorder.Interactions()))
+		}
+	}
 	m.logger.Info("rpc node manager stopped gracefully")
 }
@@ -0,0 +1,129 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrQuorumNotReached 给出相同结果的节点数不足，结果不可信
+var ErrQuorumNotReached = errors.New("rpc quorum not reached")
+
+// defaultQuarantine 与多数节点结果不一致的节点默认隔离时长
+const defaultQuarantine = 5 * time.Minute
+
+// quorumVote 一个节点的投票
+type quorumVote[T any] struct {
+	node  *Node
+	value T
+	err   error
+}
+
+// Quorum 向全部健康节点并发发起同一调用，按 key 对成功的结果分组，
+// 至少 k 个节点给出相同结果、且没有同样大的分组时返回该结果。
+// 结果与多数派不一致的节点会被隔离（Options.Quarantine 内不再被选中），请求失败的节点不参与投票也不受影响；
+// 没有分组达到 k 时返回 ErrQuorumNotReached
+func Quorum[T any](ctx context.Context, m *Manager, k int, fn func(context.Context, *Node) (T, error), key func(T) string) (T, error) {
+	var zero T
+
+	nodes := m.healthyNodes(requirementsFrom(ctx))
+	if len(nodes) < k {
+		return zero, fmt.Errorf("%w: need %d agreeing nodes, only %d healthy", ErrQuorumNotReached, k, len(nodes))
+	}
+
+	votes := make([]quorumVote[T], len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var value T
+			_, err := m.attempt(ctx, node, func(ctx context.Context, node *Node) error {
+				var err error
+				value, err = fn(ctx, node)
+				return err
+			})
+			votes[i] = quorumVote[T]{node: node, value: value, err: err}
+		}()
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return zero, ctx.Err()
+	}
+
+	// 按结果分组，记录每组的节点下标
+	var keys []string
+	groups := make(map[string][]int)
+	for i, vote := range votes {
+		if vote.err != nil {
+			m.logger.Debug("node did not vote in quorum call", "name", vote.node.Config.Name, "error", vote.err)
+			continue
+		}
+		k := key(vote.value)
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], i)
+	}
+
+	winner, tied := "", false
+	for _, k := range keys {
+		switch {
+		case len(groups[k]) > len(groups[winner]):
+			winner, tied = k, false
+		case len(groups[k]) == len(groups[winner]):
+			tied = true
+		}
+	}
+
+	if len(keys) > 1 {
+		m.logger.Warn("rpc nodes disagree", "groups", describeGroups(votes, keys, groups))
+	}
+
+	agreeing := len(groups[winner])
+	if len(keys) == 0 || tied || agreeing < k {
+		return zero, fmt.Errorf("%w: %d of %d nodes agree, need %d", ErrQuorumNotReached, agreeing, len(nodes), k)
+	}
+
+	for _, k := range keys {
+		if k == winner {
+			continue
+		}
+		for _, i := range groups[k] {
+			m.Quarantine(votes[i].node, fmt.Errorf("result disagrees with %d other nodes", agreeing))
+		}
+	}
+	return votes[groups[winner][0]].value, nil
+}
+
+// describeGroups 把各分组的节点名拼成日志友好的字符串，如 "[a b] [c]"
+func describeGroups[T any](votes []quorumVote[T], keys []string, groups map[string][]int) string {
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		names := make([]string, 0, len(groups[k]))
+		for _, i := range groups[k] {
+			names = append(names, votes[i].node.Config.Name)
+		}
+		parts = append(parts, "["+strings.Join(names, " ")+"]")
+	}
+	return strings.Join(parts, " ")
+}
+
+// Quarantine 隔离节点 Options.Quarantine 时长，期间选择节点时跳过它；到期后自动恢复，无需健康检查介入
+func (m *Manager) Quarantine(node *Node, reason error) {
+	until := time.Now().Add(m.options.Quarantine)
+
+	node.mu.Lock()
+	node.Status.QuarantinedUntil = until
+	node.Status.LastError = reason
+	node.mu.Unlock()
+
+	m.logger.Warn("node quarantined",
+		"name", node.Config.Name,
+		"until", until,
+		"reason", reason)
+}
@@ -0,0 +1,300 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrNoRecording 回放时遇到了录制文件中没有的请求
+var ErrNoRecording = errors.New("no recorded response for rpc request")
+
+// Interaction 录制文件中的一次 HTTP JSON-RPC 往返
+// Key 由方法名与参数组成（忽略请求 id），同一个 Key 按录制顺序保存多次响应，回放时依次返回
+type Interaction struct {
+	Key      string          `json:"key"`
+	Status   int             `json:"status"`
+	Response json.RawMessage `json:"response"`
+}
+
+// Recorder 包装底层 RoundTripper，把经过的 JSON-RPC 请求与响应记录下来，Save 写入录制文件
+// 响应中的 id 被替换为请求中的位置序号，回放时再换回新请求的 id，录制结果与请求 id 无关
+type Recorder struct {
+	base http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+}
+
+// NewRecorder 创建录制器，base 为 nil 时使用 http.DefaultTransport
+func NewRecorder(base http.RoundTripper) *Recorder {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Recorder{base: base}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	key, ids, err := requestKey(body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	normalized, err := rewriteIDs(respBody, func(id string) (json.RawMessage, bool) {
+		for i, reqID := range ids {
+			if reqID == id {
+				return json.RawMessage(strconv.Itoa(i)), true
+			}
+		}
+		return nil, false
+	})
+	if err != nil {
+		// 非 JSON 响应（如网关返回的 HTML 错误页）原样保存
+		normalized, _ = json.Marshal(string(respBody))
+	}
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, Interaction{Key: key, Status: resp.StatusCode, Response: normalized})
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Interactions 返回目前录制到的全部往返
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.interactions...)
+}
+
+// Save 把录制结果写入 path（JSON 数组，便于审阅与手工修改）
+func (r *Recorder) Save(path string) error {
+	js, err := json.MarshalIndent(r.Interactions(), "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(js, '\n'), 0o644)
+}
+
+// Replayer 按录制文件回放 JSON-RPC 响应的 RoundTripper，不访问网络
+// 同一个请求录制了多次响应时按顺序返回，用完后一直返回最后一次，便于模拟链头推进与重组
+type Replayer struct {
+	mu        sync.Mutex
+	responses map[string][]Interaction
+	served    map[string]int
+}
+
+// LoadReplayer 读取 Recorder.Save 写出的录制文件
+func LoadReplayer(path string) (*Replayer, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rpc recording: %w", err)
+	}
+
+	var interactions []Interaction
+	if err := json.Unmarshal(raw, &interactions); err != nil {
+		return nil, fmt.Errorf("parse rpc recording %s: %w", path, err)
+	}
+	return NewReplayer(interactions), nil
+}
+
+// NewReplayer 用内存中的往返记录创建回放器
+func NewReplayer(interactions []Interaction) *Replayer {
+	r := &Replayer{
+		responses: make(map[string][]Interaction),
+		served:    make(map[string]int),
+	}
+	for _, it := range interactions {
+		r.responses[it.Key] = append(r.responses[it.Key], it)
+	}
+	return r
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	key, ids, err := requestKey(body)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	recorded := r.responses[key]
+	n := r.served[key]
+	r.served[key]++
+	r.mu.Unlock()
+
+	if len(recorded) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoRecording, key)
+	}
+	it := recorded[min(n, len(recorded)-1)]
+
+	respBody := []byte(it.Response)
+	var raw string
+	if json.Unmarshal(it.Response, &raw) == nil {
+		respBody = []byte(raw)
+	} else if respBody, err = rewriteIDs(it.Response, func(id string) (json.RawMessage, bool) {
+		i, err := strconv.Atoi(id)
+		if err != nil || i < 0 || i >= len(ids) {
+			return nil, false
+		}
+		return json.RawMessage(ids[i]), true
+	}); err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		StatusCode:    it.Status,
+		Status:        fmt.Sprintf("%d %s", it.Status, http.StatusText(it.Status)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}, nil
+}
+
+// Pending 返回录制了但还没有被请求过的 Key，测试可以用它确认请求路径没有意外变化
+func (r *Replayer) Pending() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var keys []string
+	for key := range r.responses {
+		if r.served[key] == 0 {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// readRequestBody 读出请求体并放回，后续 RoundTripper 仍能读取
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// jsonrpcMessage 只解析匹配与改写 id 需要的字段
+type jsonrpcMessage struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// requestKey 生成与请求 id 无关的匹配键，如 `eth_getBlockByNumber["0x10",false]`；
+// 批量请求为各调用的键以 " | " 连接并加上方括号。ids 按请求中的顺序返回各调用的原始 id
+func requestKey(body []byte) (string, []string, error) {
+	body = bytes.TrimSpace(body)
+
+	var msgs []jsonrpcMessage
+	batch := len(body) > 0 && body[0] == '['
+	if batch {
+		if err := json.Unmarshal(body, &msgs); err != nil {
+			return "", nil, fmt.Errorf("decode rpc batch request: %w", err)
+		}
+	} else {
+		var msg jsonrpcMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			return "", nil, fmt.Errorf("decode rpc request: %w", err)
+		}
+		msgs = []jsonrpcMessage{msg}
+	}
+
+	keys := make([]string, len(msgs))
+	ids := make([]string, len(msgs))
+	for i, msg := range msgs {
+		params, err := canonicalJSON(msg.Params)
+		if err != nil {
+			return "", nil, err
+		}
+		keys[i] = msg.Method + params
+		ids[i] = string(msg.ID)
+	}
+
+	if batch {
+		return "[" + strings.Join(keys, " | ") + "]", ids, nil
+	}
+	return keys[0], ids, nil
+}
+
+// canonicalJSON 重新编码 JSON，去掉空白并按字母序排列对象的键
+func canonicalJSON(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return "", fmt.Errorf("decode rpc params: %w", err)
+	}
+	js, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(js), nil
+}
+
+// rewriteIDs 按 mapping 替换响应（单个或批量）中每条消息的 id，其余字段原样保留
+func rewriteIDs(body []byte, mapping func(id string) (json.RawMessage, bool)) ([]byte, error) {
+	body = bytes.TrimSpace(body)
+
+	rewrite := func(raw json.RawMessage) (json.RawMessage, error) {
+		var msg map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			return nil, err
+		}
+		if id, ok := mapping(string(msg["id"])); ok {
+			msg["id"] = id
+		}
+		return json.Marshal(msg)
+	}
+
+	if len(body) > 0 && body[0] == '[' {
+		var msgs []json.RawMessage
+		if err := json.Unmarshal(body, &msgs); err != nil {
+			return nil, err
+		}
+		for i := range msgs {
+			var err error
+			if msgs[i], err = rewrite(msgs[i]); err != nil {
+				return nil, err
+			}
+		}
+		return json.Marshal(msgs)
+	}
+	return rewrite(body)
+}
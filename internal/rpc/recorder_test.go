@@ -0,0 +1,217 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+)
+
+func newTestManager(t *testing.T, url string, opts Options) *Manager {
+	t.Helper()
+
+	m, err := NewManager([]NodeConfig{{Name: "test", URL: url}}, opts, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// fakeNode 每次 eth_blockNumber 返回递增的高度，eth_getBlockByNumber 返回 {"number": 参数}
+func fakeNode(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var height atomic.Int64
+	answer := func(raw json.RawMessage) map[string]any {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []any           `json:"params"`
+		}
+		if err := json.Unmarshal(raw, &req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+
+		resp := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+		switch req.Method {
+		case "eth_blockNumber":
+			resp["result"] = hexutil.EncodeUint64(uint64(height.Add(1)))
+		case "eth_getBlockByNumber":
+			resp["result"] = map[string]any{"number": req.Params[0]}
+		default:
+			resp["error"] = map[string]any{"code": -32601, "message": "method not found"}
+		}
+		return resp
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+
+		if body[0] == '[' {
+			var batch []json.RawMessage
+			json.Unmarshal(body, &batch)
+			out := make([]map[string]any, len(batch))
+			for i, msg := range batch {
+				out[i] = answer(msg)
+			}
+			json.NewEncoder(w).Encode(out)
+			return
+		}
+		json.NewEncoder(w).Encode(answer(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// exercise 依次发出两次 eth_blockNumber 与一次批量请求，返回观察到的结果
+func exercise(t *testing.T, m *Manager) []string {
+	t.Helper()
+	ctx := context.Background()
+
+	var got []string
+	for range 2 {
+		err := m.ExecuteContext(ctx, func(ctx context.Context, node *Node) error {
+			n, err := node.Client.BlockNumber(ctx)
+			got = append(got, hexutil.EncodeUint64(n))
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results := make([]map[string]string, 2)
+	elems := []ethrpc.BatchElem{
+		{Method: "eth_getBlockByNumber", Args: []any{"0x10", false}, Result: &results[0]},
+		{Method: "eth_getBlockByNumber", Args: []any{"0x11", false}, Result: &results[1]},
+	}
+	if err := m.BatchCall(ctx, elems); err != nil {
+		t.Fatal(err)
+	}
+	for i, elem := range elems {
+		if elem.Error != nil {
+			t.Fatalf("batch element %d: %v", i, elem.Error)
+		}
+		got = append(got, results[i]["number"])
+	}
+	return got
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.json")
+
+	recording := newTestManager(t, fakeNode(t).URL, Options{RecordFile: path})
+	recorded := exercise(t, recording)
+	recording.Stop()
+
+	want := []string{"0x1", "0x2", "0x10", "0x11"}
+	for i := range want {
+		if recorded[i] != want[i] {
+			t.Fatalf("recorded results = %v, want %v", recorded, want)
+		}
+	}
+
+	replayer, err := LoadReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 回放不访问网络，节点 URL 可以是任意不可达地址
+	replaying := newTestManager(t, "http://127.0.0.1:1", Options{Transport: replayer})
+	defer replaying.Stop()
+
+	replayed := exercise(t, replaying)
+	for i := range want {
+		if replayed[i] != want[i] {
+			t.Fatalf("replayed results = %v, want %v", replayed, want)
+		}
+	}
+	if pending := replayer.Pending(); len(pending) != 0 {
+		t.Errorf("recorded interactions never replayed: %v", pending)
+	}
+
+	// 同一请求的录制结果用完后重复最后一次
+	node, err := replaying.GetHealthyNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := node.Client.BlockNumber(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("exhausted recording returned block %d, want 2", n)
+	}
+}
+
+func TestReplayUnknownRequest(t *testing.T) {
+	replaying := newTestManager(t, "http://127.0.0.1:1", Options{Transport: NewReplayer(nil)})
+	defer replaying.Stop()
+
+	node, err := replaying.GetHealthyNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = node.Client.ChainID(context.Background())
+	if !errors.Is(err, ErrNoRecording) {
+		t.Fatalf("error = %v, want ErrNoRecording", err)
+	}
+}
+
+func TestRequestKey(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		key  string
+		ids  []string
+	}{
+		{
+			name: "single call ignores id and whitespace",
+			body: `{"jsonrpc":"2.0","id":7,"method":"eth_getBlockByNumber","params":[ "0x10", false ]}`,
+			key:  `eth_getBlockByNumber["0x10",false]`,
+			ids:  []string{"7"},
+		},
+		{
+			name: "object keys are sorted",
+			body: `{"id":1,"method":"eth_getLogs","params":[{"toBlock":"0x2","fromBlock":"0x1"}]}`,
+			key:  `eth_getLogs[{"fromBlock":"0x1","toBlock":"0x2"}]`,
+			ids:  []string{"1"},
+		},
+		{
+			name: "batch keeps call order",
+			body: `[{"id":3,"method":"eth_chainId"},{"id":4,"method":"eth_blockNumber","params":[]}]`,
+			key:  `[eth_chainId | eth_blockNumber[]]`,
+			ids:  []string{"3", "4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, ids, err := requestKey([]byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key != tt.key {
+				t.Errorf("key = %s, want %s", key, tt.key)
+			}
+			if len(ids) != len(tt.ids) {
+				t.Fatalf("ids = %v, want %v", ids, tt.ids)
+			}
+			for i := range ids {
+				if ids[i] != tt.ids[i] {
+					t.Errorf("ids = %v, want %v", ids, tt.ids)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package rpc
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 10 * time.Second
+	maxRetryAfter  = 5 * time.Minute // 防止服务商返回离谱的 Retry-After 让节点长期不可用
+)
+
+// backoff 第 attempt 次失败后的等待时间：指数退避 + 等比抖动（一半固定、一半随机），避免多个协程同时重试
+func backoff(attempt int) time.Duration {
+	d := min(retryBaseDelay<<attempt, retryMaxDelay)
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// sleepContext 等待 d，ctx 取消时立即返回
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// retryAfterTransport 包装节点的 HTTP 传输层，记录 429/503 响应中的 Retry-After，
+// 在此之前选择器会跳过该节点
+type retryAfterTransport struct {
+	base http.RoundTripper
+	node *Node
+}
+
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		now := time.Now()
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok {
+			t.node.retryAfter.Store(now.Add(d).UnixNano())
+		}
+	}
+	return resp, nil
+}
+
+// parseRetryAfter 解析秒数或 HTTP 日期两种格式的 Retry-After
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	var d time.Duration
+	if secs, err := strconv.Atoi(value); err == nil {
+		d = time.Duration(secs) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		d = at.Sub(now)
+	} else {
+		return 0, false
+	}
+
+	if d <= 0 {
+		return 0, false
+	}
+	return min(d, maxRetryAfter), true
+}
+
+// retryAfterRemaining 服务商要求的等待时间还剩多久，0 表示可以立即请求
+func (n *Node) retryAfterRemaining(now time.Time) time.Duration {
+	until := n.retryAfter.Load()
+	if until == 0 {
+		return 0
+	}
+	return max(time.Unix(0, until).Sub(now), 0)
+}
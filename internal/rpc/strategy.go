@@ -0,0 +1,181 @@
+package rpc
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// 节点选择策略
+const (
+	StrategyPriority = "priority" // 始终使用 Priority 最小的健康节点，其余节点只做故障转移
+	StrategyWeighted = "weighted" // 按 Weight 平滑加权轮询，按各家套餐额度分摊请求
+	StrategyLatency  = "latency"  // 使用 EWMA 响应时间最低的健康节点
+)
+
+// latencyAlpha 响应时间 EWMA 的平滑系数，越大越偏向最近的样本
+const latencyAlpha = 0.3
+
+// Options 节点管理器的可选配置
+type Options struct {
+	// Strategy 节点选择策略，为空时使用 StrategyPriority
+	Strategy string
+	// MaxBlockLag 节点落后已知最高区块超过该值时被标记为不健康，为 0 时使用默认值
+	MaxBlockLag uint64
+	// Breaker 每个节点的熔断器参数
+	Breaker BreakerConfig
+	// Hedge 对冲请求参数，只作用于 Hedged 发起的调用
+	Hedge HedgeConfig
+	// Quarantine Quorum 调用中结果与多数节点不一致的节点被隔离的时长，为 0 时使用默认值
+	Quarantine time.Duration
+	// Transport HTTP 节点使用的底层传输，为 nil 时使用 http.DefaultTransport；测试中可传入 Replayer 离线回放
+	Transport http.RoundTripper
+	// RecordFile 非空时录制所有 HTTP 节点的 JSON-RPC 往返，Stop 时写入该文件，供 Replayer 回放
+	RecordFile string
+	// MinNodes 至少保留的节点数（如索引器的 quorum），启动与运行时摘除节点都不能低于该值；为 0 时至少保留 1 个
+	MinNodes int
+}
+
+// Validate 校验节点管理器配置
+func (o Options) Validate() error {
+	if o.Breaker.FailureRate < 0 || o.Breaker.FailureRate > 1 {
+		return fmt.Errorf("breaker failure rate must be between 0 and 1, got %v", o.Breaker.FailureRate)
+	}
+	if o.MinNodes < 0 {
+		return fmt.Errorf("min nodes must not be negative, got %d", o.MinNodes)
+	}
+	if o.Hedge.Percentile < 0 || o.Hedge.Percentile >= 1 {
+		return fmt.Errorf("hedge percentile must be in [0, 1), got %v", o.Hedge.Percentile)
+	}
+	switch o.Strategy {
+	case "", StrategyPriority, StrategyWeighted, StrategyLatency:
+		return nil
+	default:
+		return fmt.Errorf("unsupported node selection strategy %q (expected priority, weighted or latency)", o.Strategy)
+	}
+}
+
+// candidate 选择节点时的状态快照，避免在排序过程中反复加锁
+type candidate struct {
+	node     *Node
+	priority int
+	weight   int
+	latency  time.Duration
+	caps     Capabilities
+	// throttled 令牌桶暂时没有令牌，有其它节点可用时优先绕开
+	throttled bool
+}
+
+// healthyCandidates 返回满足 req 能力要求且健康、未被隔离、熔断器放行、不在 Retry-After 等待期内、当天额度未用完的节点快照，
+// 按注册顺序排列
+func (m *Manager) healthyCandidates(req Requirements) []candidate {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// 只有至少一个节点声明了某项能力时，该项要求才生效
+	var declared Capabilities
+	for _, node := range m.nodes {
+		node.mu.RLock()
+		caps := node.Config.Capabilities
+		node.mu.RUnlock()
+		declared.Archive = declared.Archive || caps.Archive
+		declared.Traces = declared.Traces || caps.Traces
+		declared.WebSocket = declared.WebSocket || caps.WebSocket
+	}
+
+	now := time.Now()
+	var candidates []candidate
+	for _, node := range m.nodes {
+		node.mu.RLock()
+		if req.satisfiedBy(node.Config.Capabilities, declared) && node.Status.IsHealthy && !node.Status.QuarantinedUntil.After(now) && node.breaker.available(now) && node.retryAfterRemaining(now) == 0 && !node.budget.exhausted(now) {
+			candidates = append(candidates, candidate{
+				node:      node,
+				priority:  node.Config.Priority,
+				weight:    node.Config.Weight,
+				latency:   node.Status.ResponseTime,
+				caps:      node.Config.Capabilities,
+				throttled: node.budget.throttled(now),
+			})
+		}
+		node.mu.RUnlock()
+	}
+	return candidates
+}
+
+// selectNode 按配置的策略从满足 req 的健康节点中选出一个；尽量避开 exclude 中的节点（本次调用已经失败过的），
+// 所有健康节点都已尝试过时才重复使用
+func (m *Manager) selectNode(req Requirements, exclude map[*Node]bool) (*Node, error) {
+	candidates := m.healthyCandidates(req)
+	if len(candidates) == 0 {
+		return nil, ErrNoHealthyNodes
+	}
+
+	if len(exclude) > 0 {
+		fresh := slices.DeleteFunc(slices.Clone(candidates), func(c candidate) bool { return exclude[c.node] })
+		if len(fresh) > 0 {
+			candidates = fresh
+		}
+	}
+
+	// 能一次覆盖所需日志跨度的节点优先，省去分段查询
+	if covering := slices.DeleteFunc(slices.Clone(candidates), func(c candidate) bool { return !req.coversLogRange(c.caps) }); len(covering) > 0 {
+		candidates = covering
+	}
+
+	// 令牌桶见底的节点先让路，全部见底时再排队
+	if ready := slices.DeleteFunc(slices.Clone(candidates), func(c candidate) bool { return c.throttled }); len(ready) > 0 {
+		candidates = ready
+	}
+
+	if m.options.Strategy == StrategyWeighted {
+		return m.nextWeighted(candidates), nil
+	}
+
+	m.sortCandidates(candidates)
+	return candidates[0].node, nil
+}
+
+// sortCandidates 按策略排序：latency 策略按 EWMA 响应时间升序（相同则按优先级），其余按优先级升序
+// 尚未测量过响应时间的节点排在最前面，保证新节点能拿到样本
+func (m *Manager) sortCandidates(candidates []candidate) {
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		if m.options.Strategy == StrategyLatency {
+			if c := cmp.Compare(a.latency, b.latency); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(a.priority, b.priority)
+	})
+}
+
+// nextWeighted 平滑加权轮询（与 nginx upstream 相同的算法）：
+// 每轮给所有节点加上各自权重，选出当前值最大的节点并减去总权重，
+// 权重 3:1 时选择序列为 A A B A，而不是 A A A B，请求分布更均匀
+func (m *Manager) nextWeighted(candidates []candidate) *Node {
+	m.weightMu.Lock()
+	defer m.weightMu.Unlock()
+
+	var best *Node
+	bestWeight, total := 0, 0
+	for _, c := range candidates {
+		m.currentWeights[c.node] += c.weight
+		total += c.weight
+		if best == nil || m.currentWeights[c.node] > bestWeight {
+			best = c.node
+			bestWeight = m.currentWeights[c.node]
+		}
+	}
+	m.currentWeights[best] -= total
+	return best
+}
+
+// observeLatency 把一次成功请求的耗时并入 EWMA 响应时间，调用方需持有 n.mu 写锁
+func (n *Node) observeLatency(d time.Duration) {
+	if n.Status.ResponseTime == 0 {
+		n.Status.ResponseTime = d
+		return
+	}
+	n.Status.ResponseTime = time.Duration(latencyAlpha*float64(d) + (1-latencyAlpha)*float64(n.Status.ResponseTime))
+}
@@ -0,0 +1,167 @@
+package rpc
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zy99978455-otw/flash-monitor/internal/chaintest"
+)
+
+// newStrategyManager 创建使用 strategy 的管理器，第 i 个节点名为 node<i>、权重为 weights[i]、优先级为 i
+func newStrategyManager(t *testing.T, strategy string, weights ...int) *Manager {
+	t.Helper()
+
+	chain := chaintest.NewChain()
+	configs := make([]NodeConfig, len(weights))
+	for i, w := range weights {
+		configs[i] = NodeConfig{Name: fmt.Sprintf("node%d", i), URL: chaintest.NewNode(t, chain).URL(), Priority: i, Weight: w}
+	}
+	m, err := NewManager(configs, Options{Strategy: strategy}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Stop)
+	return m
+}
+
+// setHealthy 直接修改节点的健康状态，模拟健康检查的结果
+func setHealthy(t *testing.T, m *Manager, name string, healthy bool) {
+	t.Helper()
+
+	node, err := m.Node(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node.mu.Lock()
+	node.Status.IsHealthy = healthy
+	node.mu.Unlock()
+}
+
+// setLatency 把节点的 EWMA 响应时间重置为 d
+func setLatency(t *testing.T, m *Manager, name string, d time.Duration) {
+	t.Helper()
+
+	node, err := m.Node(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node.mu.Lock()
+	node.Status.ResponseTime = 0
+	node.observeLatency(d)
+	node.mu.Unlock()
+}
+
+// picks 连续选择 n 次节点，返回节点名序列
+func picks(t *testing.T, m *Manager, n int) []string {
+	t.Helper()
+
+	names := make([]string, n)
+	for i := range names {
+		node, err := m.selectNode(Requirements{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names[i] = node.Config.Name
+	}
+	return names
+}
+
+func TestWeightedSmoothRoundRobin(t *testing.T) {
+	m := newStrategyManager(t, StrategyWeighted, 3, 1)
+
+	// 3:1 的平滑加权轮询交错选择，而不是连续三次选中 node0
+	got := strings.Join(picks(t, m, 8), " ")
+	want := "node0 node0 node1 node0 node0 node0 node1 node0"
+	if got != want {
+		t.Errorf("selection sequence = %s, want %s", got, want)
+	}
+
+	counts := make(map[string]int)
+	for _, name := range picks(t, m, 400) {
+		counts[name]++
+	}
+	if counts["node0"] != 300 || counts["node1"] != 100 {
+		t.Errorf("distribution over 400 picks = %v, want node0:300 node1:100", counts)
+	}
+}
+
+func TestWeightedSkipsUnhealthyNode(t *testing.T) {
+	m := newStrategyManager(t, StrategyWeighted, 2, 1, 1)
+
+	setHealthy(t, m, "node0", false)
+	counts := make(map[string]int)
+	for _, name := range picks(t, m, 100) {
+		counts[name]++
+	}
+	// 剩余节点按各自权重平分
+	if counts["node0"] != 0 || counts["node1"] != 50 || counts["node2"] != 50 {
+		t.Errorf("distribution with node0 down = %v, want node1:50 node2:50", counts)
+	}
+
+	// 恢复后重新按 2:1:1 分配
+	setHealthy(t, m, "node0", true)
+	counts = make(map[string]int)
+	for _, name := range picks(t, m, 400) {
+		counts[name]++
+	}
+	if counts["node0"] != 200 || counts["node1"] != 100 || counts["node2"] != 100 {
+		t.Errorf("distribution after node0 recovered = %v, want node0:200 node1:100 node2:100", counts)
+	}
+}
+
+func TestLatencyPrefersFastestNode(t *testing.T) {
+	m := newStrategyManager(t, StrategyLatency, 1, 1, 1)
+
+	// 尚未测量过的节点排在最前面，保证新节点能拿到样本
+	setLatency(t, m, "node0", 100*time.Millisecond)
+	setLatency(t, m, "node1", 20*time.Millisecond)
+	if got := picks(t, m, 1)[0]; got != "node2" {
+		t.Errorf("picked %s, want the unmeasured node2", got)
+	}
+
+	setLatency(t, m, "node2", 50*time.Millisecond)
+	if got := picks(t, m, 1)[0]; got != "node1" {
+		t.Errorf("picked %s, want the fastest node1", got)
+	}
+
+	// EWMA 平滑：一次慢响应不足以让 node1 让位，持续变慢后才换到 node2
+	node1, _ := m.Node("node1")
+	node1.mu.Lock()
+	node1.observeLatency(80 * time.Millisecond)
+	ewma := node1.Status.ResponseTime
+	node1.mu.Unlock()
+	if want := time.Duration(0.3*float64(80*time.Millisecond) + 0.7*float64(20*time.Millisecond)); ewma != want {
+		t.Errorf("ewma after one slow sample = %v, want %v", ewma, want)
+	}
+	if got := picks(t, m, 1)[0]; got != "node1" {
+		t.Errorf("picked %s after one slow sample, want node1", got)
+	}
+	node1.mu.Lock()
+	for range 5 {
+		node1.observeLatency(200 * time.Millisecond)
+	}
+	node1.mu.Unlock()
+	if got := picks(t, m, 1)[0]; got != "node2" {
+		t.Errorf("picked %s after node1 slowed down, want node2", got)
+	}
+
+	// 最快的节点不健康时选次快的节点
+	setHealthy(t, m, "node2", false)
+	if got := picks(t, m, 1)[0]; got != "node0" {
+		t.Errorf("picked %s with node2 down, want node0", got)
+	}
+}
+
+func TestLatencyTieBreaksOnPriority(t *testing.T) {
+	m := newStrategyManager(t, StrategyLatency, 1, 1)
+
+	setLatency(t, m, "node0", 30*time.Millisecond)
+	setLatency(t, m, "node1", 30*time.Millisecond)
+	if got := picks(t, m, 1)[0]; got != "node0" {
+		t.Errorf("picked %s for equal latencies, want node0 by priority", got)
+	}
+}
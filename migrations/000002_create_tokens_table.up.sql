@@ -0,0 +1,15 @@
+-- 3. 创建监控代币表 (Token Registry)
+-- whale_threshold 以代币的整数单位记录（例如 50000 表示 50,000 USDT），由引擎根据 decimals 换算成链上最小单位
+CREATE TABLE IF NOT EXISTS tokens (
+    id BIGSERIAL PRIMARY KEY,
+    address VARCHAR(42) NOT NULL UNIQUE,
+    symbol VARCHAR(32) NOT NULL,
+    decimals INT NOT NULL CHECK (decimals >= 0 AND decimals <= 77),
+    whale_threshold NUMERIC NOT NULL CHECK (whale_threshold >= 0),
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+    );
+
+-- 默认继续监控 USDT，保持与 V2 版本一致的行为
+INSERT INTO tokens (address, symbol, decimals, whale_threshold)
+VALUES ('0xdAC17F958D2ee523a2206206994597C13D831ec7', 'USDT', 6, 50000)
+ON CONFLICT (address) DO NOTHING;
@@ -0,0 +1,15 @@
+-- 4. 创建历史回填任务表 (Backfill Job)
+-- next_block 是回填专用的游标，与实时同步的 block_traces 互不干扰，崩溃后从这里继续
+CREATE TABLE IF NOT EXISTS backfill_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    from_block BIGINT NOT NULL CHECK (from_block >= 0),
+    to_block BIGINT NOT NULL,
+    next_block BIGINT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT backfill_range_check CHECK (to_block >= from_block)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_backfill_jobs_status ON backfill_jobs(status);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_transfer_events_block_time;
+ALTER TABLE transfer_events DROP COLUMN IF EXISTS block_time;
+ALTER TABLE block_traces DROP COLUMN IF EXISTS block_time;
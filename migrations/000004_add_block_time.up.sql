@@ -0,0 +1,12 @@
+-- 记录区块本身的时间戳（区块头 timestamp），而不是写入数据库的时间
+-- 历史数据没有区块时间，先用写入时间近似填充，再加上非空约束
+ALTER TABLE block_traces ADD COLUMN IF NOT EXISTS block_time TIMESTAMP(0) WITH TIME ZONE;
+UPDATE block_traces SET block_time = scan_time WHERE block_time IS NULL;
+ALTER TABLE block_traces ALTER COLUMN block_time SET NOT NULL;
+
+ALTER TABLE transfer_events ADD COLUMN IF NOT EXISTS block_time TIMESTAMP(0) WITH TIME ZONE;
+UPDATE transfer_events SET block_time = created_at WHERE block_time IS NULL;
+ALTER TABLE transfer_events ALTER COLUMN block_time SET NOT NULL;
+
+-- 按时间区间查询（如“09:00 到 17:00 UTC 之间的转账”）
+CREATE INDEX IF NOT EXISTS idx_transfer_events_block_time ON transfer_events(block_time);
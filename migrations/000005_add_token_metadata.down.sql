@@ -0,0 +1,2 @@
+ALTER TABLE tokens DROP COLUMN IF EXISTS resolved_at;
+ALTER TABLE tokens DROP COLUMN IF EXISTS name;
@@ -0,0 +1,4 @@
+-- 代币元数据缓存：name 以及链上解析时间
+-- resolved_at 为空表示 symbol/decimals 仍是配置文件中的值，尚未通过 eth_call 与链上核对
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS name VARCHAR(128) NOT NULL DEFAULT '';
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS resolved_at TIMESTAMP(0) WITH TIME ZONE;
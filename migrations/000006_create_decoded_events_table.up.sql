@@ -0,0 +1,21 @@
+-- 5. 创建通用解码事件表 (Decoded Event)
+-- 由 ABI 文件注册的任意事件（Approval、Mint、Burn、协议自定义事件……）解码后统一存入 JSONB
+CREATE TABLE IF NOT EXISTS decoded_events (
+    id BIGSERIAL PRIMARY KEY,
+    tx_hash VARCHAR(66) NOT NULL,
+    log_index INT NOT NULL,
+    block_number BIGINT NOT NULL,
+    block_hash VARCHAR(66) NOT NULL,
+    contract_address VARCHAR(42) NOT NULL,
+    event_name VARCHAR(128) NOT NULL,
+    signature TEXT NOT NULL,
+    source VARCHAR(128) NOT NULL DEFAULT '',
+    payload JSONB NOT NULL,
+    block_time TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT decoded_tx_log_unique UNIQUE (tx_hash, log_index)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_decoded_events_block_number ON decoded_events(block_number);
+CREATE INDEX IF NOT EXISTS idx_decoded_events_contract_event ON decoded_events(contract_address, event_name);
+CREATE INDEX IF NOT EXISTS idx_decoded_events_payload ON decoded_events USING GIN (payload);